@@ -0,0 +1,320 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rinz5/co-finance/backend/internal/ical"
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+const (
+	defaultCalendarDays = 30
+	maxCalendarDays     = 90
+
+	economicEventDuration = 30 * time.Minute
+	economicTimeFormat    = time.DateTime
+)
+
+var earningsHours = map[string]string{
+	"bmo": "before open",
+	"amc": "after close",
+	"dmh": "during market hours",
+}
+
+// calendarRange reads from and to (YYYY-MM-DD). Both are optional: the range
+// starts today and spans defaultCalendarDays unless told otherwise.
+func calendarRange(ctx *gin.Context) (string, string, bool) {
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if raw := ctx.Query("from"); raw != "" {
+		var err error
+		if from, err = time.Parse(time.DateOnly, raw); err != nil {
+			respondBadRequest(ctx, "From must be a YYYY-MM-DD date")
+			return "", "", false
+		}
+	}
+
+	to := from.AddDate(0, 0, defaultCalendarDays)
+	if raw := ctx.Query("to"); raw != "" {
+		var err error
+		if to, err = time.Parse(time.DateOnly, raw); err != nil {
+			respondBadRequest(ctx, "To must be a YYYY-MM-DD date")
+			return "", "", false
+		}
+	}
+
+	if to.Before(from) {
+		respondBadRequest(ctx, "From must not be after to")
+		return "", "", false
+	}
+	if to.Sub(from) > maxCalendarDays*24*time.Hour {
+		respondBadRequest(ctx, fmt.Sprintf("Range must not exceed %d days", maxCalendarDays))
+		return "", "", false
+	}
+
+	return from.Format(time.DateOnly), to.Format(time.DateOnly), true
+}
+
+// symbolFilter returns the upper-cased symbols from a comma-separated symbol
+// parameter, or nil when there is no filter.
+func symbolFilter(ctx *gin.Context) map[string]bool {
+	symbols := splitList(ctx.Query("symbol"))
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	filter := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		filter[strings.ToUpper(symbol)] = true
+	}
+	return filter
+}
+
+// wantsICS reports whether the request is for the .ics variant of a route.
+func wantsICS(ctx *gin.Context) bool {
+	return strings.HasSuffix(ctx.Request.URL.Path, ".ics")
+}
+
+func respondICS(ctx *gin.Context, name string, events []ical.Event) {
+	cal := ical.Calendar{Name: name, Events: events}
+
+	ctx.Header("Content-Type", "text/calendar; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.ics"`, strings.ToLower(strings.ReplaceAll(name, " ", "-"))))
+	ctx.Status(http.StatusOK)
+	if err := cal.Write(ctx.Writer); err != nil {
+		log.Printf("%s %s failed: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+	}
+}
+
+func (s *Server) handleEarningsCalendar(ctx *gin.Context) {
+	from, to, ok := calendarRange(ctx)
+	if !ok {
+		return
+	}
+	filter := symbolFilter(ctx)
+
+	// Finnhub filters on a single symbol; anything else is filtered here.
+	upstreamSymbol := ""
+	if len(filter) == 1 {
+		for symbol := range filter {
+			upstreamSymbol = symbol
+		}
+	}
+
+	releases, err := s.client.GetEarningsCalendarContext(ctx.Request.Context(), from, to, upstreamSymbol)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	filtered := make([]models.EarningsRelease, 0, len(releases))
+	for _, release := range releases {
+		if filter == nil || filter[release.Symbol] {
+			filtered = append(filtered, release)
+		}
+	}
+	slices.SortFunc(filtered, func(a, b models.EarningsRelease) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.Symbol, b.Symbol))
+	})
+
+	if !wantsICS(ctx) {
+		ctx.JSON(http.StatusOK, filtered)
+		return
+	}
+
+	events := make([]ical.Event, 0, len(filtered))
+	for _, release := range filtered {
+		day, err := time.Parse(time.DateOnly, release.Date)
+		if err != nil {
+			continue
+		}
+
+		summary := fmt.Sprintf("%s Q%d %d earnings", release.Symbol, release.Quarter, release.Year)
+		if hour, ok := earningsHours[release.Hour]; ok {
+			summary += " (" + hour + ")"
+		}
+
+		events = append(events, ical.Event{
+			// Keyed by fiscal quarter so a rescheduled release moves the
+			// existing entry instead of adding another.
+			UID:     fmt.Sprintf("earnings-%s-%dQ%d@co-finance", release.Symbol, release.Year, release.Quarter),
+			Summary: summary,
+			Description: describe(
+				figure{"EPS estimate", release.EpsEstimate},
+				figure{"EPS actual", release.EpsActual},
+				figure{"Revenue estimate", release.RevenueEstimate},
+				figure{"Revenue actual", release.RevenueActual},
+			),
+			Start:  day,
+			AllDay: true,
+		})
+	}
+
+	respondICS(ctx, "Earnings", events)
+}
+
+func (s *Server) handleIPOCalendar(ctx *gin.Context) {
+	from, to, ok := calendarRange(ctx)
+	if !ok {
+		return
+	}
+	filter := symbolFilter(ctx)
+
+	ipos, err := s.client.GetIPOCalendarContext(ctx.Request.Context(), from, to)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	filtered := make([]models.IPOEvent, 0, len(ipos))
+	for _, ipo := range ipos {
+		if filter == nil || filter[ipo.Symbol] {
+			filtered = append(filtered, ipo)
+		}
+	}
+	slices.SortFunc(filtered, func(a, b models.IPOEvent) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.Name, b.Name))
+	})
+
+	if !wantsICS(ctx) {
+		ctx.JSON(http.StatusOK, filtered)
+		return
+	}
+
+	events := make([]ical.Event, 0, len(filtered))
+	for _, ipo := range filtered {
+		day, err := time.Parse(time.DateOnly, ipo.Date)
+		if err != nil {
+			continue
+		}
+
+		summary := "IPO: " + ipo.Name
+		if ipo.Symbol != "" {
+			summary += " (" + ipo.Symbol + ")"
+		}
+
+		details := []string{ipo.Exchange, "Status " + ipo.Status}
+		if ipo.Price != "" {
+			details = append(details, "Price "+ipo.Price)
+		}
+		if ipo.NumberOfShares != nil {
+			details = append(details, "Shares "+formatAmount(*ipo.NumberOfShares))
+		}
+
+		events = append(events, ical.Event{
+			UID:         fmt.Sprintf("ipo-%s@co-finance", cmp.Or(ipo.Symbol, ipo.Name)),
+			Summary:     summary,
+			Description: strings.Join(details, "\n"),
+			Start:       day,
+			AllDay:      true,
+		})
+	}
+
+	respondICS(ctx, "IPOs", events)
+}
+
+func (s *Server) handleEconomicCalendar(ctx *gin.Context) {
+	from, to, ok := calendarRange(ctx)
+	if !ok {
+		return
+	}
+
+	var countries map[string]bool
+	if list := splitList(ctx.Query("country")); len(list) > 0 {
+		countries = make(map[string]bool, len(list))
+		for _, country := range list {
+			countries[strings.ToUpper(country)] = true
+		}
+	}
+
+	calendar, err := s.client.GetEconomicCalendarContext(ctx.Request.Context(), from, to)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	filtered := make([]models.EconomicEvent, 0, len(calendar))
+	for _, event := range calendar {
+		if countries == nil || countries[event.Country] {
+			filtered = append(filtered, event)
+		}
+	}
+	slices.SortFunc(filtered, func(a, b models.EconomicEvent) int {
+		return cmp.Or(cmp.Compare(a.Time, b.Time), cmp.Compare(a.Country, b.Country), cmp.Compare(a.Event, b.Event))
+	})
+
+	if !wantsICS(ctx) {
+		ctx.JSON(http.StatusOK, filtered)
+		return
+	}
+
+	events := make([]ical.Event, 0, len(filtered))
+	for _, event := range filtered {
+		start, err := time.Parse(economicTimeFormat, event.Time)
+		if err != nil {
+			continue
+		}
+
+		description := describe(
+			figure{"Estimate", event.Estimate},
+			figure{"Actual", event.Actual},
+			figure{"Previous", event.Prev},
+		)
+		if event.Impact != "" {
+			description = strings.TrimSpace(description + "\nImpact " + event.Impact)
+		}
+
+		events = append(events, ical.Event{
+			UID:         fmt.Sprintf("economic-%s-%s-%s@co-finance", event.Country, event.Event, start.Format("20060102T1504")),
+			Summary:     fmt.Sprintf("%s: %s", event.Country, event.Event),
+			Description: description,
+			Start:       start,
+			Duration:    economicEventDuration,
+		})
+	}
+
+	respondICS(ctx, "Economic calendar", events)
+}
+
+// figure is a labelled value for an event description; nil values are not
+// known yet.
+type figure struct {
+	label string
+	value *float64
+}
+
+func describe(figures ...figure) string {
+	var lines []string
+	for _, f := range figures {
+		if f.value != nil {
+			lines = append(lines, f.label+" "+formatAmount(*f.value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatAmount abbreviates large values, e.g. 117.9B, and prints small ones
+// as is.
+func formatAmount(v float64) string {
+	abs := v
+	if abs < 0 {
+		abs = -abs
+	}
+
+	switch {
+	case abs >= 1e9:
+		return strconv.FormatFloat(v/1e9, 'f', 1, 64) + "B"
+	case abs >= 1e6:
+		return strconv.FormatFloat(v/1e6, 'f', 1, 64) + "M"
+	default:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestHandleEarningsCalendar(t *testing.T) {
+	r := newTestRouter(&fakeProvider{earningsCal: []models.EarningsRelease{
+		{Date: "2024-02-01", EpsEstimate: ptr(2.1), RevenueEstimate: ptr(117.9e9), Hour: "amc", Quarter: 1, Symbol: "AAPL", Year: 2024},
+		{Date: "2024-01-30", EpsEstimate: ptr(2.78), Hour: "amc", Quarter: 2, Symbol: "MSFT", Year: 2024},
+		{Date: "2024-01-25", Hour: "amc", Quarter: 4, Symbol: "INTC", Year: 2023},
+	}})
+
+	w := get(r, "/api/calendar/earnings?from=2024-01-20&to=2024-02-10&symbol=aapl,MSFT")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var releases []models.EarningsRelease
+	if err := json.Unmarshal(w.Body.Bytes(), &releases); err != nil {
+		t.Fatalf("Expected earnings calendar JSON, got %s", w.Body.String())
+	}
+	if len(releases) != 2 || releases[0].Symbol != "MSFT" || releases[1].Symbol != "AAPL" {
+		t.Errorf("Expected MSFT then AAPL, got %+v", releases)
+	}
+	if releases[0].EpsActual != nil {
+		t.Errorf("Expected unreported EPS to stay null, got %v", *releases[0].EpsActual)
+	}
+
+	w = get(r, "/api/calendar/earnings.ics?from=2024-01-20&to=2024-02-10&symbol=AAPL")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("Expected an iCalendar response, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"UID:earnings-AAPL-2024Q1@co-finance\r\n",
+		"DTSTART;VALUE=DATE:20240201\r\n",
+		"SUMMARY:AAPL Q1 2024 earnings (after close)\r\n",
+		`DESCRIPTION:EPS estimate 2.1\nRevenue estimate 117.9B` + "\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "MSFT") {
+		t.Error("Expected the symbol filter to apply to the export")
+	}
+}
+
+func TestHandleEconomicCalendar(t *testing.T) {
+	r := newTestRouter(&fakeProvider{economicCal: []models.EconomicEvent{
+		{Actual: ptr(3.4), Country: "US", Estimate: ptr(3.2), Event: "CPI YoY", Impact: "high", Prev: ptr(3.1), Time: "2024-01-11 13:30:00", Unit: "%"},
+		{Country: "GB", Event: "GDP MoM", Impact: "medium", Time: "2024-01-12 07:00:00"},
+	}})
+
+	w := get(r, "/api/calendar/economic?from=2024-01-08&to=2024-01-14&country=us")
+	var events []models.EconomicEvent
+	json.Unmarshal(w.Body.Bytes(), &events)
+	if w.Code != http.StatusOK || len(events) != 1 || events[0].Event != "CPI YoY" {
+		t.Errorf("Expected only the US event, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = get(r, "/api/calendar/economic.ics?from=2024-01-08&to=2024-01-14")
+	body := w.Body.String()
+	for _, want := range []string{
+		"DTSTART:20240111T133000Z\r\nDTEND:20240111T140000Z\r\n",
+		"SUMMARY:GB: GDP MoM\r\n",
+		"DESCRIPTION:Impact medium\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in\n%s", want, body)
+		}
+	}
+}
+
+func TestHandleIPOCalendar(t *testing.T) {
+	r := newTestRouter(&fakeProvider{ipoCal: []models.IPOEvent{
+		{Date: "2024-01-12", Exchange: "NASDAQ Global", Name: "CG ONCOLOGY INC", Price: "16.00-18.00", Status: "expected", Symbol: "CGON"},
+		{Date: "2024-01-10", Exchange: "NYSE", Name: "UNNAMED SPAC", Status: "filed"},
+	}})
+
+	w := get(r, "/api/calendar/ipo.ics?from=2024-01-08&to=2024-01-14")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || strings.Count(body, "BEGIN:VEVENT") != 2 {
+		t.Fatalf("Expected 2 IPO events, got %d: %s", w.Code, body)
+	}
+	if !strings.Contains(body, "SUMMARY:IPO: CG ONCOLOGY INC (CGON)\r\n") || !strings.Contains(body, "UID:ipo-UNNAMED SPAC@co-finance\r\n") {
+		t.Errorf("Unexpected IPO export\n%s", body)
+	}
+}
+
+func TestCalendarRangeValidation(t *testing.T) {
+	r := newTestRouter(&fakeProvider{})
+
+	for _, url := range []string{
+		"/api/calendar/earnings?from=tomorrow",
+		"/api/calendar/ipo?from=2024-02-01&to=2024-01-01",
+		"/api/calendar/economic?from=2024-01-01&to=2024-12-31",
+	} {
+		if w := get(r, url); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+
+	if w := get(r, "/api/calendar/earnings"); w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Errorf("Expected an empty default range to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+}
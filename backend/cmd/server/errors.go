@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rinz5/co-finance/backend/internal/finnhub"
+	"github.com/rinz5/co-finance/backend/internal/provider"
+)
+
+const (
+	CodeBadRequest           = "bad_request"
+	CodeNotFound             = "not_found"
+	CodeNoData               = "no_data"
+	CodeRateLimited          = "rate_limited"
+	CodeUpstreamUnauthorized = "upstream_unauthorized"
+	CodeUpstreamUnavailable  = "upstream_unavailable"
+	CodeUpstreamDecode       = "upstream_decode_error"
+	CodeUpstreamTimeout      = "upstream_timeout"
+	CodeClientClosed         = "client_closed_request"
+	CodeInternal             = "internal_error"
+
+	// StatusClientClosedRequest is nginx's non-standard status for requests
+	// the client abandoned; it only ever shows up in logs.
+	StatusClientClosedRequest = 499
+)
+
+// errorMessages are the messages sent for each code. Upstream errors can
+// carry request URLs, including the API key, so their text is only logged.
+var errorMessages = map[string]string{
+	CodeBadRequest:           "Invalid request parameters",
+	CodeNotFound:             "Symbol not found",
+	CodeNoData:               "No data for the requested range",
+	CodeRateLimited:          "Rate limit reached, try again later",
+	CodeUpstreamUnauthorized: "Market data provider rejected our credentials",
+	CodeUpstreamUnavailable:  "Market data provider is unavailable",
+	CodeUpstreamDecode:       "Market data provider sent an invalid response",
+	CodeUpstreamTimeout:      "Market data provider timed out",
+	CodeClientClosed:         "Request cancelled",
+	CodeInternal:             "Internal server error",
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func respondBadRequest(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: message, Code: CodeBadRequest})
+}
+
+// respondError maps upstream failures onto the status a browser should see:
+// our own quota or credentials problems are not the caller's fault, so only
+// rate limiting and unknown symbols surface as 4xx.
+func respondError(ctx *gin.Context, err error) {
+	status, code := classifyError(err)
+
+	var apiErr *finnhub.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(apiErr.RetryAfter.Seconds())))
+	}
+
+	if status >= http.StatusInternalServerError {
+		log.Printf("%s %s failed: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+	}
+
+	ctx.JSON(status, ErrorResponse{Error: errorMessages[code], Code: code})
+}
+
+func classifyError(err error) (int, string) {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, finnhub.ErrRateLimited):
+		return http.StatusTooManyRequests, CodeRateLimited
+	case errors.Is(err, provider.ErrInvalidRequest):
+		return http.StatusBadRequest, CodeBadRequest
+	case errors.Is(err, provider.ErrNoData):
+		return http.StatusNotFound, CodeNoData
+	case errors.Is(err, provider.ErrNotFound):
+		return http.StatusNotFound, CodeNotFound
+	case errors.Is(err, finnhub.ErrUnauthorized):
+		return http.StatusBadGateway, CodeUpstreamUnauthorized
+	case errors.Is(err, finnhub.ErrDecode):
+		return http.StatusBadGateway, CodeUpstreamDecode
+	case errors.Is(err, finnhub.ErrUpstreamUnavailable), errors.Is(err, finnhub.ErrUnexpectedStatus),
+		errors.Is(err, provider.ErrUnavailable):
+		return http.StatusBadGateway, CodeUpstreamUnavailable
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, CodeClientClosed
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout, CodeUpstreamTimeout
+	case errors.As(err, &netErr):
+		return http.StatusBadGateway, CodeUpstreamUnavailable
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
@@ -1,24 +1,68 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/rinz5/co-finance/backend/internal/bars"
 	"github.com/rinz5/co-finance/backend/internal/finnhub"
 	"github.com/rinz5/co-finance/backend/internal/models"
+	"github.com/rinz5/co-finance/backend/internal/news"
+	"github.com/rinz5/co-finance/backend/internal/offline"
+	"github.com/rinz5/co-finance/backend/internal/provider"
+	"github.com/rinz5/co-finance/backend/internal/recording"
+	"github.com/rinz5/co-finance/backend/internal/simulator"
+	"github.com/rinz5/co-finance/backend/internal/symbols"
 	"github.com/rinz5/co-finance/backend/internal/websocket"
 
 	ws "github.com/gorilla/websocket"
 )
 
-const ErrSymbolRequired = "Symbol is required"
+const (
+	ErrSymbolRequired = "Symbol is required"
+
+	shutdownTimeout    = 10 * time.Second
+	barCloseDelay      = 2 * time.Second
+	defaultBarsLimit   = 100
+	maxCandles         = 5000
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	maxSearchQuery     = 32
+	defaultNewsDays    = 7
+	maxReplaySteps     = 1000
+)
+
+var newsCategories = []string{"general", "forex", "crypto", "merger"}
+
+// candleResolutions maps Finnhub's candle resolutions to their approximate
+// bar width, used to reject ranges that would return too many bars.
+var candleResolutions = map[string]time.Duration{
+	"1":  time.Minute,
+	"5":  5 * time.Minute,
+	"15": 15 * time.Minute,
+	"30": 30 * time.Minute,
+	"60": time.Hour,
+	"D":  24 * time.Hour,
+	"W":  7 * 24 * time.Hour,
+	"M":  30 * 24 * time.Hour,
+}
 
 type DashboardResponse struct {
 	Quote           *models.StockQuote           `json:"quote"`
@@ -26,12 +70,36 @@ type DashboardResponse struct {
 	Earnings        []models.EarningsSurprise    `json:"earnings"`
 	Recommendations []models.RecommendationTrend `json:"recommendations"`
 	Insiders        []models.InsiderTransaction  `json:"insiders"`
+	Profile         *models.CompanyProfile       `json:"profile,omitempty"`
+}
+
+type BarMessage struct {
+	Type string `json:"type"`
+	bars.Event
+}
+
+type NewsMessage struct {
+	Type   string               `json:"type"`
+	Symbol string               `json:"symbol,omitempty"`
+	Items  []models.CompanyNews `json:"items"`
 }
 
 type Server struct {
-	hub      *websocket.Hub
-	streamer *finnhub.StreamClient
-	client   *finnhub.Client
+	hub       *websocket.Hub
+	streamer  *finnhub.StreamClient
+	recorder  *recording.Recorder
+	replayer  *recording.Replayer
+	simulator *simulator.Simulator
+	client    provider.MarketDataProvider
+	api       *finnhub.Client
+	cache     *finnhub.CachedClient
+	failover  *provider.Failover
+	bars      *bars.Aggregator
+	symbols   *symbols.Index
+	news      *news.Poller
+	stories   *news.Registry
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 func initializeEnvironment() string {
@@ -41,29 +109,440 @@ func initializeEnvironment() string {
 	}
 
 	apiKey := os.Getenv("FINNHUB_API_KEY")
-	if apiKey == "" {
+	if apiKey == "" && !offlineMode() {
 		log.Fatal("Error: FINNHUB_API_KEY is not set.")
 	}
 
 	return apiKey
 }
 
+func offlineMode() bool {
+	return os.Getenv("DATA_PROVIDER") == "offline"
+}
+
 func setupServer(apiKey string) *Server {
 	hub := websocket.NewHub()
+	hub.AllowWildcard = os.Getenv("WS_ALLOW_WILDCARD") == "true"
+	hub.ClientConfig = setupClientConfig()
 	go hub.Run()
 
+	server := &Server{
+		hub:     hub,
+		bars:    bars.NewAggregator(bars.DefaultIntervals, bars.DefaultHistory),
+		stories: news.NewRegistry(news.DefaultRegistrySize),
+		done:    make(chan struct{}),
+	}
+
+	server.failover = provider.NewFailover(
+		server.setupSources(apiKey),
+		getEnvInt("BREAKER_FAILURE_THRESHOLD", provider.DefaultFailureThreshold),
+		time.Duration(getEnvInt("BREAKER_COOLDOWN_SECONDS", int(provider.DefaultCooldown.Seconds())))*time.Second,
+	)
+	server.failover.MaxQuoteAge = time.Duration(getEnvInt("QUOTE_MAX_AGE_SECONDS", 0)) * time.Second
+	server.client = server.failover
+	server.setupSymbols()
+	server.setupNews()
+
+	frames := make(chan []byte)
+	switch getEnv("STREAM_SOURCE", defaultStreamSource()) {
+	case "simulator":
+		server.setupSimulator(frames)
+	case "replay":
+		server.setupReplay(frames)
+	default:
+		server.setupStreamer(apiKey, frames)
+	}
+	go server.relayStream(frames)
+	go server.flushBars()
+
+	return server
+}
+
+// setupSources returns the data sources in the order they are tried: the one
+// DATA_PROVIDER selects, then those listed in FALLBACK_SOURCES.
+func (s *Server) setupSources(apiKey string) []provider.Source {
+	primary := "finnhub"
+	if offlineMode() {
+		primary = "offline"
+	}
+
+	var sources []provider.Source
+	for _, name := range append([]string{primary}, splitList(os.Getenv("FALLBACK_SOURCES"))...) {
+		if slices.ContainsFunc(sources, func(src provider.Source) bool { return src.Name == name }) {
+			continue
+		}
+
+		switch name {
+		case "finnhub":
+			if apiKey == "" {
+				log.Println("Warning: skipping the finnhub source, FINNHUB_API_KEY is not set")
+				continue
+			}
+			sources = append(sources, s.setupFinnhub(apiKey))
+		case "offline":
+			dir := getEnv("OFFLINE_DATA_DIR", "fixtures")
+			log.Printf("Serving offline data from %s", dir)
+			sources = append(sources, provider.Source{Name: "offline", Provider: offline.NewProvider(dir)})
+		default:
+			log.Printf("Warning: ignoring unknown data source %q", name)
+		}
+	}
+
+	return sources
+}
+
+// setupFinnhub builds the REST client and returns the cached client as a data
+// source.
+func (s *Server) setupFinnhub(apiKey string) provider.Source {
+	limits := finnhub.DefaultRateLimits()
+	api := finnhub.NewClient(apiKey)
+	api.BaseURL = getEnv("FINNHUB_BASE_URL", api.BaseURL)
+	api.Limiter = finnhub.NewRateLimiter(finnhub.RateLimits{
+		PerSecond: getEnvInt("FINNHUB_RATE_PER_SECOND", limits.PerSecond),
+		PerMinute: getEnvInt("FINNHUB_RATE_PER_MINUTE", limits.PerMinute),
+	})
+	api.Retry.MaxAttempts = getEnvInt("FINNHUB_MAX_ATTEMPTS", api.Retry.MaxAttempts)
+	s.api = api
+	s.cache = finnhub.NewCachedClient(api, finnhub.DefaultCacheTTLs(), getEnvInt("CACHE_MAX_ENTRIES", finnhub.DefaultCacheSize))
+
+	return provider.Source{Name: "finnhub", Provider: s.cache}
+}
+
+// setupSymbols loads the persisted symbol index and keeps it fresh in the
+// background. Offline listings are already on disk, so they are not persisted
+// again.
+func (s *Server) setupSymbols() {
+	dir := getEnv("SYMBOL_INDEX_DIR", "data/symbols")
+	if offlineMode() {
+		dir = ""
+	}
+
+	var exchanges []string
+	for _, exchange := range strings.Split(getEnv("SYMBOL_EXCHANGES", "US"), ",") {
+		if exchange = strings.TrimSpace(exchange); exchange != "" {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+
+	// The index keeps listings itself, so they bypass the response cache.
+	var source symbols.Source = s.client
+	if s.api != nil {
+		source = s.api
+	}
+
+	index := symbols.NewIndex(source, dir, exchanges)
+	index.Context = background
+	if err := index.Load(); err != nil {
+		log.Printf("Warning: failed to load symbol index: %v", err)
+	}
+	s.symbols = index
+
+	go index.Start()
+}
+
+// setupNews polls news for the symbols clients are subscribed to and pushes
+// new stories over the websocket. It polls Finnhub directly when it can, as
+// the cache would hold stories back for the news TTL.
+func (s *Server) setupNews() {
+	interval := getEnvInt("NEWS_POLL_SECONDS", int(news.DefaultPollInterval.Seconds()))
+	if interval <= 0 {
+		return
+	}
+
+	var source news.Source = s.client
+	if s.api != nil {
+		source = s.api
+	}
+
+	poller := news.NewPoller(source, s.hub.Symbols, s.publishNews)
+	poller.Interval = time.Duration(interval) * time.Second
+	poller.Registry = s.stories
+	poller.Context = background
+	s.news = poller
+
+	go poller.Start()
+}
+
+// background tags requests no user is waiting on, so they yield to
+// interactive ones at the rate limiter.
+func background(ctx context.Context) context.Context {
+	return finnhub.WithPriority(ctx, finnhub.PriorityBackground)
+}
+
+// close stops the background work and finishes the stream recording, so its
+// last frames and gzip trailer are written.
+func (s *Server) close() {
+	if s.news != nil {
+		s.news.Stop()
+	}
+	if s.symbols != nil {
+		s.symbols.Stop()
+	}
+	if s.simulator != nil {
+		s.simulator.Stop()
+	}
+	if s.streamer != nil {
+		s.streamer.Stop()
+	}
+	if s.replayer != nil {
+		s.replayer.Stop()
+	}
+	if s.done != nil {
+		s.closeOnce.Do(func() { close(s.done) })
+	}
+	if s.recorder != nil {
+		if err := s.recorder.Close(); err != nil {
+			log.Printf("Stream recording close error: %v", err)
+		}
+	}
+}
+
+func defaultStreamSource() string {
+	if offlineMode() {
+		return "simulator"
+	}
+	return "finnhub"
+}
+
+func (s *Server) setupStreamer(apiKey string, frames chan<- []byte) {
 	streamer := finnhub.NewStreamClient(apiKey, []string{"AAPL"})
-	go streamer.Start(hub.Broadcast)
+	streamer.BaseURL = getEnv("FINNHUB_STREAM_URL", streamer.BaseURL)
+	s.hub.Upstream = streamer
+	s.streamer = streamer
+
+	streamer.OnStateChange = func(state finnhub.ConnState) {
+		log.Printf("Finnhub stream state: %s", state)
+		s.hub.SetFeedStatus(state.String())
+	}
+
+	if path := os.Getenv("STREAM_RECORD_PATH"); path != "" {
+		recorder, err := recording.NewRecorder(path)
+		if err != nil {
+			log.Fatalf("Failed to open stream recording: %v", err)
+		}
+		log.Printf("Recording Finnhub stream to %s", path)
+		s.recorder = recorder
+
+		streamer.OnFrame = func(received time.Time, frame []byte) {
+			if err := recorder.Record(received, frame); err != nil {
+				log.Printf("Stream recording error: %v", err)
+			}
+		}
+	}
+
+	go streamer.Start(frames)
+}
+
+// setupReplay feeds the hub a session recorded with STREAM_RECORD_PATH.
+func (s *Server) setupReplay(frames chan<- []byte) {
+	path := os.Getenv("REPLAY_PATH")
+	if path == "" {
+		log.Fatal("Error: REPLAY_PATH is not set.")
+	}
+
+	replayer := recording.NewReplayer(path)
+	if raw := os.Getenv("REPLAY_SPEED"); raw != "" {
+		speed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || speed < 0 {
+			log.Printf("Warning: invalid REPLAY_SPEED %q, using %g", raw, replayer.Speed)
+		} else {
+			replayer.Speed = speed
+		}
+	}
+
+	switch mode := getEnv("REPLAY_MODE", "timed"); mode {
+	case "step":
+		replayer.Stepwise = true
+		log.Printf("Replaying %s one frame per POST /api/replay/step", path)
+	case "timed":
+		log.Printf("Replaying %s at %gx", path, replayer.Speed)
+	default:
+		log.Fatalf("Error: unknown REPLAY_MODE %q.", mode)
+	}
+	s.replayer = replayer
+	s.hub.SetFeedStatus("replay")
+
+	go func() {
+		if err := replayer.Start(frames); err != nil {
+			log.Printf("Replay error: %v", err)
+		}
+	}()
+}
+
+// setupSimulator feeds the hub synthetic trades that start from each symbol's
+// current quote.
+func (s *Server) setupSimulator(frames chan<- []byte) {
+	seed := uint64(time.Now().UnixNano())
+	if raw := os.Getenv("SIM_SEED"); raw != "" {
+		if value, err := strconv.ParseUint(raw, 10, 64); err == nil {
+			seed = value
+		} else {
+			log.Printf("Warning: invalid SIM_SEED %q, using %d", raw, seed)
+		}
+	}
+
+	sim := simulator.New([]string{"AAPL"}, seed)
+	sim.Volatility = getEnvFloat("SIM_VOLATILITY", sim.Volatility)
+	sim.TickInterval = time.Duration(getEnvInt("SIM_TICK_MS", int(sim.TickInterval.Milliseconds()))) * time.Millisecond
+	if raw := os.Getenv("SIM_MODEL"); raw != "" {
+		model, err := simulator.ParseModel(raw)
+		if err != nil {
+			log.Printf("Warning: %v, using %s", err, sim.Model)
+		} else {
+			sim.Model = model
+		}
+	}
+
+	sim.InitialPrice = func(symbol string) float64 {
+		ctx, cancel := context.WithTimeout(background(context.Background()), 5*time.Second)
+		defer cancel()
+
+		quote, err := s.client.GetQuoteContext(ctx, symbol)
+		if err != nil {
+			return 0
+		}
+		return quote.CurrentPrice
+	}
+
+	log.Printf("Streaming simulated trades (%s, seed %d)", sim.Model, seed)
+	s.hub.Upstream = sim
+	s.simulator = sim
+	s.hub.SetFeedStatus("simulated")
+
+	go sim.Start(frames)
+}
+
+// relayStream decodes upstream frames and forwards validated trades to the
+// hub, so browsers never see Finnhub pings or malformed data.
+func (s *Server) relayStream(frames <-chan []byte) {
+	for raw := range frames {
+		frame, err := finnhub.DecodeFrame(raw)
+		if err != nil {
+			log.Printf("Stream frame error: %v", err)
+			continue
+		}
 
-	client := finnhub.NewClient(apiKey)
+		switch frame.Type {
+		case finnhub.FrameTrade:
+			trades := finnhub.ValidTrades(frame.Trades)
+			if len(trades) == 0 {
+				continue
+			}
+
+			message, err := finnhub.EncodeTrades(trades)
+			if err != nil {
+				log.Printf("Trade encode error: %v", err)
+				continue
+			}
+			s.hub.Broadcast <- message
+
+			for _, trade := range trades {
+				s.publishBars(s.bars.Add(trade))
+			}
+		case finnhub.FrameError:
+			log.Printf("Finnhub stream error: %s", frame.Message)
+		}
+	}
+}
 
-	return &Server{
-		hub:      hub,
-		streamer: streamer,
-		client:   client,
+// flushBars closes bars of quiet symbols once their period is over. Closing
+// waits barCloseDelay so slightly late trades still land in their bar.
+func (s *Server) flushBars() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.publishBars(s.bars.Flush(now.Add(-barCloseDelay)))
+		case <-s.done:
+			return
+		}
 	}
 }
 
+func (s *Server) publishBars(events []bars.Event) {
+	for _, event := range events {
+		data, err := json.Marshal(BarMessage{Type: "bar", Event: event})
+		if err != nil {
+			log.Printf("Bar encode error: %v", err)
+			continue
+		}
+
+		s.hub.Publish <- websocket.Message{
+			Symbol: event.Symbol,
+			Key:    fmt.Sprintf("bar:%s:%s:%s", event.Type, event.Symbol, event.Interval),
+			Data:   data,
+		}
+	}
+}
+
+// publishNews sends new stories to the clients subscribed to their symbol, or
+// to every client for general news. News is never coalesced, since a lagging
+// client would lose stories.
+func (s *Server) publishNews(update news.Update) {
+	data, err := json.Marshal(NewsMessage{Type: "news", Symbol: update.Symbol, Items: update.Items})
+	if err != nil {
+		log.Printf("News encode error: %v", err)
+		return
+	}
+
+	s.hub.Publish <- websocket.Message{Symbol: update.Symbol, Data: data}
+}
+
+func getEnv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvFloat(name string, fallback float64) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value <= 0 {
+		log.Printf("Warning: invalid %s %q, using %g", name, raw, fallback)
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvInt(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		log.Printf("Warning: invalid %s %q, using %d", name, raw, fallback)
+		return fallback
+	}
+
+	return value
+}
+
+func setupClientConfig() websocket.ClientConfig {
+	config := websocket.DefaultClientConfig()
+	config.QueueSize = getEnvInt("WS_SEND_QUEUE_SIZE", config.QueueSize)
+
+	if raw := os.Getenv("WS_OVERFLOW_POLICY"); raw != "" {
+		policy, err := websocket.ParseOverflowPolicy(raw)
+		if err != nil {
+			log.Printf("Warning: %v, using %s", err, config.Policy)
+		} else {
+			config.Policy = policy
+		}
+	}
+
+	return config
+}
+
 func setupOrigins() (map[string]bool, []string) {
 	rawOrigins := os.Getenv("ALLOWED_ORIGINS")
 	if rawOrigins == "" {
@@ -107,11 +586,12 @@ func (s *Server) setupWebSocketHandler(wsAllowedOrigins map[string]bool) gin.Han
 			return
 		}
 
-		s.hub.Register <- conn
+		client := websocket.NewClient(conn, s.hub.ClientConfig)
+		s.hub.Register <- client
 
 		defer func() {
-			s.hub.Unregister <- conn
-			conn.Close()
+			s.hub.Unregister <- client
+			client.Close()
 		}()
 
 		for {
@@ -120,13 +600,21 @@ func (s *Server) setupWebSocketHandler(wsAllowedOrigins map[string]bool) gin.Han
 				Symbol string `json:"symbol"`
 			}
 
-			if err := conn.ReadJSON(&msg); err != nil {
+			if err := client.ReadJSON(&msg); err != nil {
 				break
 			}
 
-			if msg.Type == "subscribe" && msg.Symbol != "" {
+			if msg.Symbol == "" {
+				continue
+			}
+
+			switch msg.Type {
+			case "subscribe":
 				log.Printf("Frontend requested subscription to: %s", msg.Symbol)
-				s.streamer.Subscribe(msg.Symbol)
+				s.hub.Subscribe <- websocket.Subscription{Client: client, Symbol: msg.Symbol}
+			case "unsubscribe":
+				log.Printf("Frontend requested unsubscription from: %s", msg.Symbol)
+				s.hub.Unsubscribe <- websocket.Subscription{Client: client, Symbol: msg.Symbol}
 			}
 		}
 	}
@@ -135,7 +623,7 @@ func (s *Server) setupWebSocketHandler(wsAllowedOrigins map[string]bool) gin.Han
 func (s *Server) validateSymbol(ctx *gin.Context) (string, bool) {
 	symbol := ctx.Query("symbol")
 	if symbol == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": ErrSymbolRequired})
+		respondBadRequest(ctx, ErrSymbolRequired)
 		return "", false
 	}
 	return symbol, true
@@ -147,9 +635,9 @@ func (s *Server) handleQuote(ctx *gin.Context) {
 		return
 	}
 
-	quote, err := s.client.GetQuote(symbol)
+	quote, err := s.client.GetQuoteContext(ctx.Request.Context(), symbol)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -162,24 +650,42 @@ func (s *Server) handleFinancials(ctx *gin.Context) {
 		return
 	}
 
-	financials, err := s.client.GetBasicFinancials(symbol)
+	financials, err := s.client.GetBasicFinancialsContext(ctx.Request.Context(), symbol)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
+	metrics := splitList(ctx.Query("metrics"))
+	series := splitList(ctx.Query("series"))
+	if metrics != nil || series != nil {
+		financials = financials.Select(metrics, series)
+	}
+
 	ctx.JSON(http.StatusOK, financials)
 }
 
+// splitList parses a comma-separated query value, returning nil when it is
+// empty.
+func splitList(raw string) []string {
+	var values []string
+	for value := range strings.SplitSeq(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func (s *Server) handleEarnings(ctx *gin.Context) {
 	symbol, ok := s.validateSymbol(ctx)
 	if !ok {
 		return
 	}
 
-	earnings, err := s.client.GetEarnings(symbol)
+	earnings, err := s.client.GetEarningsContext(ctx.Request.Context(), symbol)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -192,9 +698,9 @@ func (s *Server) handleRecommendations(ctx *gin.Context) {
 		return
 	}
 
-	recommendations, err := s.client.GetRecommendations(symbol)
+	recommendations, err := s.client.GetRecommendationsContext(ctx.Request.Context(), symbol)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -207,9 +713,9 @@ func (s *Server) handleInsider(ctx *gin.Context) {
 		return
 	}
 
-	transactions, err := s.client.GetInsiderTransactions(symbol)
+	transactions, err := s.client.GetInsiderTransactionsContext(ctx.Request.Context(), symbol)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -224,82 +730,371 @@ func (s *Server) handleDashboard(ctx *gin.Context) {
 
 	var res DashboardResponse
 
-	g, _ := errgroup.WithContext(ctx.Request.Context())
+	g, gctx := errgroup.WithContext(ctx.Request.Context())
 
 	g.Go(func() error {
 		var err error
-		res.Quote, err = s.client.GetQuote(symbol)
+		res.Quote, err = s.client.GetQuoteContext(gctx, symbol)
 		return err
 	})
 
 	g.Go(func() error {
 		var err error
-		res.Financials, err = s.client.GetBasicFinancials(symbol)
+		res.Financials, err = s.client.GetBasicFinancialsContext(gctx, symbol)
 		return err
 	})
 
 	g.Go(func() error {
 		var err error
-		res.Earnings, err = s.client.GetEarnings(symbol)
+		res.Earnings, err = s.client.GetEarningsContext(gctx, symbol)
 		return err
 	})
 
 	g.Go(func() error {
 		var err error
-		res.Recommendations, err = s.client.GetRecommendations(symbol)
+		res.Recommendations, err = s.client.GetRecommendationsContext(gctx, symbol)
 		return err
 	})
 
 	g.Go(func() error {
 		var err error
-		res.Insiders, err = s.client.GetInsiderTransactions(symbol)
+		res.Insiders, err = s.client.GetInsiderTransactionsContext(gctx, symbol)
+		return err
+	})
+
+	// Funds and some foreign listings have no profile; the rest of the
+	// dashboard is still worth showing.
+	g.Go(func() error {
+		var err error
+		res.Profile, err = s.client.GetCompanyProfileContext(gctx, symbol)
+		if errors.Is(err, provider.ErrNotFound) {
+			return nil
+		}
 		return err
 	})
 
 	if err := g.Wait(); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dashboard data: " + err.Error()})
+		respondError(ctx, fmt.Errorf("failed to fetch dashboard data: %w", err))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, res)
 }
 
+func (s *Server) handleProfile(ctx *gin.Context) {
+	symbol, ok := s.validateSymbol(ctx)
+	if !ok {
+		return
+	}
+
+	profile, err := s.client.GetCompanyProfileContext(ctx.Request.Context(), symbol)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+func (s *Server) handlePeers(ctx *gin.Context) {
+	symbol, ok := s.validateSymbol(ctx)
+	if !ok {
+		return
+	}
+
+	peers, err := s.client.GetPeersContext(ctx.Request.Context(), symbol)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, peers)
+}
+
+// handleCompanyNews serves a symbol's news between from and to (YYYY-MM-DD).
+// Without to the range ends today, and without from it covers the
+// defaultNewsDays before to.
 func (s *Server) handleCompanyNews(ctx *gin.Context) {
-	symbol := ctx.Query("symbol")
-	from := ctx.Query("from")
-	to := ctx.Query("to")
+	symbol, ok := s.validateSymbol(ctx)
+	if !ok {
+		return
+	}
+
+	to := time.Now().UTC()
+	if raw := ctx.Query("to"); raw != "" {
+		var err error
+		if to, err = time.Parse(time.DateOnly, raw); err != nil {
+			respondBadRequest(ctx, "To must be a YYYY-MM-DD date")
+			return
+		}
+	}
 
-	if symbol == "" || from == "" || to == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Symbol, from, and to parameters are required"})
+	from := to.AddDate(0, 0, -defaultNewsDays)
+	if raw := ctx.Query("from"); raw != "" {
+		var err error
+		if from, err = time.Parse(time.DateOnly, raw); err != nil {
+			respondBadRequest(ctx, "From must be a YYYY-MM-DD date")
+			return
+		}
+	}
+
+	if from.After(to) {
+		respondBadRequest(ctx, "From must not be after to")
+		return
+	}
+
+	items, err := s.client.GetCompanyNewsContext(ctx.Request.Context(), symbol, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, s.stories.Canonicalize(news.CompanyFeed(symbol), items))
+}
+
+// handleNews serves general market news for a category. Pass the highest id
+// already shown as minId to get only newer stories; stories republished under
+// a newer id are left out when the client already has them.
+func (s *Server) handleNews(ctx *gin.Context) {
+	category := ctx.DefaultQuery("category", "general")
+	if !slices.Contains(newsCategories, category) {
+		respondBadRequest(ctx, "Category must be one of "+strings.Join(newsCategories, ", "))
 		return
 	}
 
-	news, err := s.client.GetCompanyNews(symbol, from, to)
+	var minID int64
+	if raw := ctx.Query("minId"); raw != "" {
+		var err error
+		minID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || minID < 0 {
+			respondBadRequest(ctx, "MinId must be a non-negative integer")
+			return
+		}
+	}
+
+	items, err := s.client.GetMarketNewsContext(ctx.Request.Context(), category, minID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, news)
+	items = slices.DeleteFunc(s.stories.Canonicalize(news.MarketFeed(category), items), func(item models.CompanyNews) bool {
+		return minID > 0 && item.Id <= minID
+	})
+	ctx.JSON(http.StatusOK, items)
 }
 
 func (s *Server) handleMarketStatus(ctx *gin.Context) {
 	exchange := ctx.Query("exchange")
 
 	if exchange == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Exchange parametes is required"})
+		respondBadRequest(ctx, "Exchange parametes is required")
 		return
 	}
 
-	market, err := s.client.GetMarketStatus(exchange)
+	market, err := s.client.GetMarketStatusContext(ctx.Request.Context(), exchange)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, market)
 }
 
+func (s *Server) handleBars(ctx *gin.Context) {
+	symbol, ok := s.validateSymbol(ctx)
+	if !ok {
+		return
+	}
+
+	interval, err := s.bars.ParseInterval(ctx.DefaultQuery("interval", "1m"))
+	if err != nil {
+		respondBadRequest(ctx, err.Error())
+		return
+	}
+
+	limit := defaultBarsLimit
+	if raw := ctx.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			respondBadRequest(ctx, "Limit must be a positive integer")
+			return
+		}
+	}
+
+	current, closed := s.bars.Bars(symbol, interval, limit)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"symbol":   symbol,
+		"interval": bars.FormatInterval(interval),
+		"current":  current,
+		"bars":     closed,
+	})
+}
+
+func (s *Server) handleCandles(ctx *gin.Context) {
+	symbol, ok := s.validateSymbol(ctx)
+	if !ok {
+		return
+	}
+
+	resolution := ctx.DefaultQuery("resolution", "D")
+	width, ok := candleResolutions[resolution]
+	if !ok {
+		respondBadRequest(ctx, "Resolution must be one of 1, 5, 15, 30, 60, D, W or M")
+		return
+	}
+
+	if ctx.Query("from") == "" {
+		respondBadRequest(ctx, "From parameter is required")
+		return
+	}
+	from, err := parseTime(ctx.Query("from"))
+	if err != nil {
+		respondBadRequest(ctx, "From must be a unix timestamp or YYYY-MM-DD date")
+		return
+	}
+
+	to := time.Now()
+	if raw := ctx.Query("to"); raw != "" {
+		if to, err = parseTime(raw); err != nil {
+			respondBadRequest(ctx, "To must be a unix timestamp or YYYY-MM-DD date")
+			return
+		}
+	}
+
+	if !from.Before(to) {
+		respondBadRequest(ctx, "From must be before to")
+		return
+	}
+	if to.Sub(from)/width > maxCandles {
+		respondBadRequest(ctx, fmt.Sprintf("Range is too large for resolution %s (max %d bars)", resolution, maxCandles))
+		return
+	}
+
+	candles, err := s.client.GetCandlesContext(ctx.Request.Context(), symbol, resolution, from, to)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	bars, err := candles.Bars()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"symbol":     symbol,
+		"resolution": resolution,
+		"bars":       bars,
+	})
+}
+
+// parseTime accepts unix seconds or a YYYY-MM-DD date (UTC midnight).
+func parseTime(raw string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+func (s *Server) handleSearch(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" {
+		respondBadRequest(ctx, "Query parameter q is required")
+		return
+	}
+	if len(query) > maxSearchQuery {
+		respondBadRequest(ctx, fmt.Sprintf("Query must be at most %d characters", maxSearchQuery))
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := ctx.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxSearchLimit {
+			respondBadRequest(ctx, fmt.Sprintf("Limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+	}
+
+	var results []symbols.Match
+	if s.symbols != nil {
+		results = s.symbols.Search(query, limit)
+	}
+
+	// Until the index has loaded, or when it knows nothing that matches, ask
+	// Finnhub directly.
+	if len(results) == 0 {
+		lookup, err := s.client.SearchSymbolsContext(ctx.Request.Context(), query)
+		if err != nil {
+			respondError(ctx, err)
+			return
+		}
+
+		found := make([]models.StockSymbol, len(lookup.Result))
+		for i, result := range lookup.Result {
+			found[i] = models.StockSymbol{
+				Description:   result.Description,
+				DisplaySymbol: result.DisplaySymbol,
+				Symbol:        result.Symbol,
+				Type:          result.Type,
+			}
+		}
+		results = symbols.Rank(query, found, limit)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"query":   query,
+		"results": results,
+	})
+}
+
+// handleReplayStep releases the next count frames (default 1) of a stepwise
+// replay. finished reports that the log ran out before count frames.
+func (s *Server) handleReplayStep(ctx *gin.Context) {
+	count := 1
+	if raw := ctx.Query("count"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxReplaySteps {
+			respondBadRequest(ctx, fmt.Sprintf("Count must be between 1 and %d", maxReplaySteps))
+			return
+		}
+		count = n
+	}
+
+	stepped := 0
+	for stepped < count && s.replayer.Step() {
+		stepped++
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"stepped": stepped, "finished": stepped < count})
+}
+
+func (s *Server) handleStats(ctx *gin.Context) {
+	stats := gin.H{"websocket": s.hub.Stats()}
+
+	if s.cache != nil {
+		stats["cache"] = s.cache.Stats()
+	}
+	if s.failover != nil {
+		stats["sources"] = s.failover.Stats()
+	}
+	if s.symbols != nil {
+		stats["symbols"] = s.symbols.Stats()
+	}
+	if s.api != nil {
+		stats["retries"] = s.api.RetryStats()
+		if s.api.Limiter != nil {
+			stats["rateLimit"] = s.api.Limiter.Stats()
+		}
+	}
+
+	ctx.JSON(http.StatusOK, stats)
+}
+
 func (s *Server) setupRoutes(r *gin.Engine) {
 	wsAllowedOrigins, _ := setupOrigins()
 
@@ -310,8 +1105,25 @@ func (s *Server) setupRoutes(r *gin.Engine) {
 	r.GET("/api/recommendations", s.handleRecommendations)
 	r.GET("/api/insider", s.handleInsider)
 	r.GET("/api/dashboard", s.handleDashboard)
+	r.GET("/api/profile", s.handleProfile)
+	r.GET("/api/peers", s.handlePeers)
 	r.GET("/api/company-news", s.handleCompanyNews)
+	r.GET("/api/news", s.handleNews)
 	r.GET("/api/market-status", s.handleMarketStatus)
+	r.GET("/api/bars", s.handleBars)
+	r.GET("/api/candles", s.handleCandles)
+	r.GET("/api/search", s.handleSearch)
+	r.GET("/api/calendar/earnings", s.handleEarningsCalendar)
+	r.GET("/api/calendar/earnings.ics", s.handleEarningsCalendar)
+	r.GET("/api/calendar/ipo", s.handleIPOCalendar)
+	r.GET("/api/calendar/ipo.ics", s.handleIPOCalendar)
+	r.GET("/api/calendar/economic", s.handleEconomicCalendar)
+	r.GET("/api/calendar/economic.ics", s.handleEconomicCalendar)
+	r.GET("/api/stats", s.handleStats)
+
+	if s.replayer != nil && s.replayer.Stepwise {
+		r.POST("/api/replay/step", s.handleReplayStep)
+	}
 }
 
 func main() {
@@ -328,8 +1140,23 @@ func main() {
 
 	server.setupRoutes(r)
 
-	log.Println("Server running on http://localhost:8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal("Failed to start server:", err)
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		log.Println("Server running on http://localhost:8080")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
 	}
+	server.close()
 }
@@ -0,0 +1,765 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+
+	"github.com/rinz5/co-finance/backend/internal/finnhub"
+	"github.com/rinz5/co-finance/backend/internal/finnhub/finnhubtest"
+	"github.com/rinz5/co-finance/backend/internal/models"
+	"github.com/rinz5/co-finance/backend/internal/news"
+	"github.com/rinz5/co-finance/backend/internal/provider"
+	"github.com/rinz5/co-finance/backend/internal/recording"
+	"github.com/rinz5/co-finance/backend/internal/symbols"
+)
+
+type fakeProvider struct {
+	quote           *models.StockQuote
+	financials      *models.BasicFinancials
+	earnings        []models.EarningsSurprise
+	recommendations []models.RecommendationTrend
+	insiders        []models.InsiderTransaction
+	news            []models.CompanyNews
+	newsRange       [2]string
+	marketStatus    *models.MarketStatus
+	candles         *models.Candles
+	profile         *models.CompanyProfile
+	peers           []string
+	lookup          *models.SymbolLookup
+	symbols         []models.StockSymbol
+	earningsCal     []models.EarningsRelease
+	ipoCal          []models.IPOEvent
+	economicCal     []models.EconomicEvent
+	err             error
+}
+
+func (f *fakeProvider) GetQuoteContext(ctx context.Context, symbol string) (*models.StockQuote, error) {
+	return f.quote, f.err
+}
+
+func (f *fakeProvider) GetBasicFinancialsContext(ctx context.Context, symbol string) (*models.BasicFinancials, error) {
+	return f.financials, f.err
+}
+
+func (f *fakeProvider) GetEarningsContext(ctx context.Context, symbol string) ([]models.EarningsSurprise, error) {
+	return f.earnings, f.err
+}
+
+func (f *fakeProvider) GetRecommendationsContext(ctx context.Context, symbol string) ([]models.RecommendationTrend, error) {
+	return f.recommendations, f.err
+}
+
+func (f *fakeProvider) GetInsiderTransactionsContext(ctx context.Context, symbol string) ([]models.InsiderTransaction, error) {
+	return f.insiders, f.err
+}
+
+func (f *fakeProvider) GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error) {
+	f.newsRange = [2]string{from, to}
+	return f.news, f.err
+}
+
+func (f *fakeProvider) GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error) {
+	return f.news, f.err
+}
+
+func (f *fakeProvider) GetMarketStatusContext(ctx context.Context, exchange string) (*models.MarketStatus, error) {
+	return f.marketStatus, f.err
+}
+
+func (f *fakeProvider) GetCandlesContext(ctx context.Context, symbol, resolution string, from, to time.Time) (*models.Candles, error) {
+	return f.candles, f.err
+}
+
+func (f *fakeProvider) GetCompanyProfileContext(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	return f.profile, f.err
+}
+
+func (f *fakeProvider) GetPeersContext(ctx context.Context, symbol string) ([]string, error) {
+	return f.peers, f.err
+}
+
+func (f *fakeProvider) SearchSymbolsContext(ctx context.Context, query string) (*models.SymbolLookup, error) {
+	return f.lookup, f.err
+}
+
+func (f *fakeProvider) GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error) {
+	return f.symbols, f.err
+}
+
+func (f *fakeProvider) GetEarningsCalendarContext(ctx context.Context, from, to, symbol string) ([]models.EarningsRelease, error) {
+	return f.earningsCal, f.err
+}
+
+func (f *fakeProvider) GetIPOCalendarContext(ctx context.Context, from, to string) ([]models.IPOEvent, error) {
+	return f.ipoCal, f.err
+}
+
+func (f *fakeProvider) GetEconomicCalendarContext(ctx context.Context, from, to string) ([]models.EconomicEvent, error) {
+	return f.economicCal, f.err
+}
+
+func newTestRouter(client provider.MarketDataProvider) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	server := &Server{client: client, stories: news.NewRegistry(news.DefaultRegistrySize)}
+	r := gin.New()
+	server.setupRoutes(r)
+	return r
+}
+
+func get(r *gin.Engine, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	return w
+}
+
+func TestHandleQuote(t *testing.T) {
+	r := newTestRouter(&fakeProvider{quote: &models.StockQuote{CurrentPrice: 261.74}})
+
+	w := get(r, "/api/quote?symbol=AAPL")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var quote models.StockQuote
+	if err := json.Unmarshal(w.Body.Bytes(), &quote); err != nil {
+		t.Fatalf("Expected JSON quote, got %s", w.Body.String())
+	}
+	if quote.CurrentPrice != 261.74 {
+		t.Errorf("Expected price 261.74, got %f", quote.CurrentPrice)
+	}
+}
+
+func TestHandleQuoteRequiresSymbol(t *testing.T) {
+	r := newTestRouter(&fakeProvider{})
+
+	w := get(r, "/api/quote")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	var body ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Code != CodeBadRequest || body.Error != ErrSymbolRequired {
+		t.Errorf("Expected bad request envelope, got %+v", body)
+	}
+}
+
+func TestHandlerErrorMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"rate limited", &finnhub.APIError{Kind: finnhub.ErrRateLimited, StatusCode: 429, RetryAfter: 30 * time.Second}, http.StatusTooManyRequests, CodeRateLimited},
+		{"unknown symbol", &finnhub.APIError{Kind: finnhub.ErrNotFound, StatusCode: 200}, http.StatusNotFound, CodeNotFound},
+		{"unauthorized", &finnhub.APIError{Kind: finnhub.ErrUnauthorized, StatusCode: 401}, http.StatusBadGateway, CodeUpstreamUnauthorized},
+		{"unavailable", &finnhub.APIError{Kind: finnhub.ErrUpstreamUnavailable, StatusCode: 503}, http.StatusBadGateway, CodeUpstreamUnavailable},
+		{"decode", &finnhub.APIError{Kind: finnhub.ErrDecode, StatusCode: 200}, http.StatusBadGateway, CodeUpstreamDecode},
+		{"timeout", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeUpstreamTimeout},
+		{"invalid request", fmt.Errorf("offline: from date %q: %w", "jan", provider.ErrInvalidRequest), http.StatusBadRequest, CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(&fakeProvider{err: tt.err})
+
+			w := get(r, "/api/dashboard?symbol=AAPL")
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+
+			var body ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Expected error envelope, got %s", w.Body.String())
+			}
+			if body.Code != tt.code {
+				t.Errorf("Expected code %s, got %s", tt.code, body.Code)
+			}
+		})
+	}
+}
+
+func TestHandlerErrorHidesUpstreamDetails(t *testing.T) {
+	err := &url.Error{Op: "Get", URL: "https://finnhub.io/api/v1/quote?symbol=AAPL&token=secret-key", Err: errors.New("connection refused")}
+	r := newTestRouter(&fakeProvider{err: err})
+
+	w := get(r, "/api/quote?symbol=AAPL")
+
+	if strings.Contains(w.Body.String(), "secret-key") {
+		t.Fatalf("Expected the API key not to reach the client, got %s", w.Body.String())
+	}
+
+	var body ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusBadGateway || body.Code != CodeUpstreamUnavailable || body.Error != errorMessages[CodeUpstreamUnavailable] {
+		t.Errorf("Expected the generic upstream_unavailable envelope, got %d %+v", w.Code, body)
+	}
+}
+
+func TestHandlerSetsRetryAfter(t *testing.T) {
+	r := newTestRouter(&fakeProvider{err: &finnhub.APIError{Kind: finnhub.ErrRateLimited, StatusCode: 429, RetryAfter: 30 * time.Second}})
+
+	w := get(r, "/api/quote?symbol=AAPL")
+
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestHandleFinancialsSelection(t *testing.T) {
+	financials := &models.BasicFinancials{
+		Metric: models.Metrics{"beta": 1.29, "peBasicExclExtraTTM": 29.47, "roeTTM": 1.56},
+		Series: models.FinancialSeries{
+			Annual:    map[string][]models.SeriesPoint{"currentRatio": {{Period: "2023-09-30", V: 0.988}}, "netMargin": {{Period: "2023-09-30", V: 0.2531}}},
+			Quarterly: map[string][]models.SeriesPoint{"currentRatio": {{Period: "2023-09-30", V: 0.988}}},
+		},
+		Symbol: "AAPL",
+	}
+	r := newTestRouter(&fakeProvider{financials: financials})
+
+	w := get(r, "/api/financials?symbol=AAPL&metrics=roeTTM,%20beta&series=currentRatio")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var res models.BasicFinancials
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Expected financials JSON, got %s", w.Body.String())
+	}
+
+	if v, _ := res.Metric.Float("roeTTM"); v != 1.56 || res.Beta() != 1.29 || res.PeBasicExclExtraTTM() != 0 {
+		t.Errorf("Expected only roeTTM and beta, got %v", res.Metric)
+	}
+	if res.AnnualSeries("netMargin") != nil || len(res.QuarterlySeries("currentRatio")) != 1 {
+		t.Errorf("Expected only currentRatio series, got %+v", res.Series)
+	}
+	if len(financials.Metric) != 3 {
+		t.Error("Expected the provider's value to be left untouched")
+	}
+}
+
+func TestHandleCandles(t *testing.T) {
+	candles := &models.Candles{
+		Close: []float64{217.68, 221.03}, High: []float64{222.49, 221.5}, Low: []float64{217.19, 217.1402},
+		Open: []float64{221.03, 218.55}, Status: "ok", Timestamp: []int64{1569297600, 1569384000}, Volume: []float64{33463820, 24018876},
+	}
+	r := newTestRouter(&fakeProvider{candles: candles})
+
+	w := get(r, "/api/candles?symbol=AAPL&resolution=D&from=2019-09-24&to=1569470400")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var res struct {
+		Symbol     string       `json:"symbol"`
+		Resolution string       `json:"resolution"`
+		Bars       []models.Bar `json:"bars"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Expected candles JSON, got %s", w.Body.String())
+	}
+	if res.Symbol != "AAPL" || res.Resolution != "D" || len(res.Bars) != 2 {
+		t.Fatalf("Unexpected candles response %+v", res)
+	}
+	if !res.Bars[0].Time.Equal(time.Unix(1569297600, 0)) || res.Bars[1].Close != 221.03 {
+		t.Errorf("Unexpected bars %+v", res.Bars)
+	}
+}
+
+func TestHandleCandlesValidation(t *testing.T) {
+	r := newTestRouter(&fakeProvider{})
+
+	for _, url := range []string{
+		"/api/candles?symbol=AAPL&resolution=2&from=2024-01-01",
+		"/api/candles?symbol=AAPL&resolution=D",
+		"/api/candles?symbol=AAPL&resolution=D&from=yesterday",
+		"/api/candles?symbol=AAPL&resolution=D&from=2024-01-02&to=2024-01-01",
+		"/api/candles?symbol=AAPL&resolution=1&from=2024-01-01&to=2024-03-01",
+	} {
+		if w := get(r, url); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleCandlesNoData(t *testing.T) {
+	r := newTestRouter(&fakeProvider{err: &finnhub.APIError{Kind: finnhub.ErrNoData, StatusCode: 200}})
+
+	w := get(r, "/api/candles?symbol=AAPL&from=2024-01-06&to=2024-01-07")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	var body ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body.Code != CodeNoData {
+		t.Errorf("Expected code %s, got %s", CodeNoData, body.Code)
+	}
+}
+
+func TestHandleSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := &fakeProvider{
+		symbols: []models.StockSymbol{
+			{Description: "APPLE INC", DisplaySymbol: "AAPL", Mic: "XNAS", Symbol: "AAPL", Type: "Common Stock"},
+			{Description: "APPLE HOSPITALITY REIT INC", DisplaySymbol: "APLE", Mic: "XNYS", Symbol: "APLE", Type: "REIT"},
+		},
+		lookup: &models.SymbolLookup{Count: 1, Result: []models.SymbolLookupResult{
+			{Description: "TOYOTA MOTOR CORP", DisplaySymbol: "7203.T", Symbol: "7203.T", Type: "Common Stock"},
+		}},
+	}
+
+	index := symbols.NewIndex(fake, "", []string{"US"})
+	if err := index.Refresh(context.Background(), "US"); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &Server{client: fake, symbols: index}
+	r := gin.New()
+	server.setupRoutes(r)
+
+	var res struct {
+		Query   string          `json:"query"`
+		Results []symbols.Match `json:"results"`
+	}
+
+	w := get(r, "/api/search?q=apple")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if len(res.Results) != 2 || res.Results[0].Symbol != "AAPL" || res.Results[0].Exchange != "US" {
+		t.Errorf("Expected AAPL then APLE from the index, got %+v", res.Results)
+	}
+
+	w = get(r, "/api/search?q=toyota")
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || len(res.Results) != 1 || res.Results[0].Symbol != "7203.T" {
+		t.Errorf("Expected the upstream result for an unindexed query, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, url := range []string{"/api/search", "/api/search?q=%20", "/api/search?q=apple&limit=0", "/api/search?q=apple&limit=500",
+		"/api/search?q=" + strings.Repeat("a", maxSearchQuery+1)} {
+		if w := get(r, url); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleCompanyNewsDefaultsRange(t *testing.T) {
+	fake := &fakeProvider{news: []models.CompanyNews{
+		{Id: 1, Headline: "Apple beats", Url: "https://finnhub.io/api/news?id=a"},
+		{Id: 1, Headline: "Apple beats", Url: "https://finnhub.io/api/news?id=a"},
+		{Id: 2, Headline: "Apple beats (update)", Url: "https://finnhub.io/api/news?id=a"},
+	}}
+	r := newTestRouter(fake)
+
+	w := get(r, "/api/company-news?symbol=AAPL")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	today := time.Now().UTC()
+	want := [2]string{today.AddDate(0, 0, -defaultNewsDays).Format(time.DateOnly), today.Format(time.DateOnly)}
+	if fake.newsRange != want {
+		t.Errorf("Expected range %v, got %v", want, fake.newsRange)
+	}
+
+	var items []models.CompanyNews
+	json.Unmarshal(w.Body.Bytes(), &items)
+	if len(items) != 1 {
+		t.Errorf("Expected duplicates to be dropped, got %+v", items)
+	}
+
+	get(r, "/api/company-news?symbol=AAPL&to=2024-01-31")
+	if fake.newsRange != [2]string{"2024-01-24", "2024-01-31"} {
+		t.Errorf("Expected the week before to, got %v", fake.newsRange)
+	}
+
+	for _, url := range []string{"/api/company-news", "/api/company-news?symbol=AAPL&from=2024-02-01&to=2024-01-01", "/api/company-news?symbol=AAPL&from=jan"} {
+		if w := get(r, url); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleNews(t *testing.T) {
+	r := newTestRouter(&fakeProvider{news: []models.CompanyNews{
+		{Category: "crypto", Id: 7, Headline: "Bitcoin rallies", Url: "https://example.com/btc"},
+		{Category: "crypto", Id: 6, Headline: "Bitcoin rallies", Url: "https://example.com/btc?utm_source=x"},
+	}})
+
+	w := get(r, "/api/news?category=crypto&minId=5")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var items []models.CompanyNews
+	json.Unmarshal(w.Body.Bytes(), &items)
+	if len(items) != 1 || items[0].Id != 7 {
+		t.Errorf("Expected one deduplicated story, got %+v", items)
+	}
+
+	for _, url := range []string{"/api/news?category=sports", "/api/news?minId=-1", "/api/news?minId=abc"} {
+		if w := get(r, url); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleNewsAcrossRequests(t *testing.T) {
+	fake := &fakeProvider{news: []models.CompanyNews{
+		{Id: 1, Headline: "Apple beats", Url: "https://example.com/apple-beats"},
+	}}
+	r := newTestRouter(fake)
+
+	get(r, "/api/company-news?symbol=AAPL")
+	get(r, "/api/news?category=general")
+
+	// Finnhub republishes the story under a new id alongside a new one.
+	fake.news = []models.CompanyNews{
+		{Id: 5, Headline: "Apple beats", Url: "https://example.com/apple-beats/?utm_medium=rss"},
+		{Id: 6, Headline: "Apple guides higher", Url: "https://example.com/apple-guides"},
+	}
+
+	var items []models.CompanyNews
+	json.Unmarshal(get(r, "/api/company-news?symbol=AAPL").Body.Bytes(), &items)
+	if len(items) != 2 || items[0].Id != 1 || items[1].Id != 6 {
+		t.Errorf("Expected the republished story to keep id 1, got %+v", items)
+	}
+
+	json.Unmarshal(get(r, "/api/news?category=general&minId=1").Body.Bytes(), &items)
+	if len(items) != 1 || items[0].Id != 6 {
+		t.Errorf("Expected only the new story after minId 1, got %+v", items)
+	}
+}
+
+func TestHandleDashboard(t *testing.T) {
+	r := newTestRouter(&fakeProvider{
+		quote:           &models.StockQuote{CurrentPrice: 261.74},
+		financials:      &models.BasicFinancials{Symbol: "AAPL"},
+		earnings:        []models.EarningsSurprise{{Actual: 1.88}},
+		recommendations: []models.RecommendationTrend{{Buy: 24}},
+		insiders:        []models.InsiderTransaction{{Name: "Kirkhorn Zachary"}},
+		profile:         &models.CompanyProfile{Name: "Apple Inc", Ticker: "AAPL"},
+	})
+
+	w := get(r, "/api/dashboard?symbol=AAPL")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var res DashboardResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Expected dashboard JSON, got %s", w.Body.String())
+	}
+
+	if res.Quote.CurrentPrice != 261.74 || res.Financials.Symbol != "AAPL" || len(res.Earnings) != 1 ||
+		len(res.Recommendations) != 1 || len(res.Insiders) != 1 || res.Profile == nil || res.Profile.Name != "Apple Inc" {
+		t.Errorf("Unexpected dashboard %+v", res)
+	}
+}
+
+func TestHandleProfileAndPeers(t *testing.T) {
+	r := newTestRouter(&fakeProvider{
+		profile: &models.CompanyProfile{Name: "Apple Inc", Ticker: "AAPL", Exchange: "NASDAQ NMS - GLOBAL MARKET"},
+		peers:   []string{"AAPL", "DELL", "HPQ"},
+	})
+
+	w := get(r, "/api/profile?symbol=AAPL")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"Apple Inc"`) {
+		t.Errorf("Expected the AAPL profile, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = get(r, "/api/peers?symbol=AAPL")
+	if w.Code != http.StatusOK || w.Body.String() != `["AAPL","DELL","HPQ"]` {
+		t.Errorf("Expected the AAPL peers, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := get(r, "/api/peers"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without symbol, got %d", w.Code)
+	}
+}
+
+func TestHandleDashboardOffline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DATA_PROVIDER", "offline")
+	t.Setenv("OFFLINE_DATA_DIR", "../../fixtures")
+	t.Setenv("STREAM_SOURCE", "simulator")
+	t.Setenv("NEWS_POLL_SECONDS", "0")
+
+	server := setupServer("")
+	defer server.close()
+	r := gin.New()
+	server.setupRoutes(r)
+
+	w := get(r, "/api/dashboard?symbol=AAPL")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var res DashboardResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Expected dashboard JSON, got %s", w.Body.String())
+	}
+
+	if res.Quote.Source != "offline" || res.Financials.Symbol != "AAPL" || len(res.Earnings) == 0 ||
+		len(res.Recommendations) == 0 || len(res.Insiders) == 0 || res.Profile == nil {
+		t.Errorf("Unexpected dashboard %+v", res)
+	}
+
+	if w := get(r, "/api/quote?symbol=NOPE"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown symbol, got %d", w.Code)
+	}
+}
+
+func TestServerFallsBackToOfflineSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+	fake.SetError("/quote", "", http.StatusServiceUnavailable)
+
+	t.Setenv("FINNHUB_BASE_URL", fake.URL)
+	t.Setenv("FINNHUB_MAX_ATTEMPTS", "1")
+	t.Setenv("FALLBACK_SOURCES", "offline")
+	t.Setenv("OFFLINE_DATA_DIR", "../../fixtures")
+	t.Setenv("STREAM_SOURCE", "simulator")
+	t.Setenv("SYMBOL_INDEX_DIR", t.TempDir())
+	t.Setenv("NEWS_POLL_SECONDS", "0")
+
+	server := setupServer("fake-key")
+	defer server.close()
+
+	r := gin.New()
+	server.setupRoutes(r)
+
+	w := get(r, "/api/quote?symbol=AAPL")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the offline source to serve, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var quote models.StockQuote
+	json.Unmarshal(w.Body.Bytes(), &quote)
+	if quote.Source != "offline" {
+		t.Errorf("Expected source offline, got %q", quote.Source)
+	}
+	if fake.RequestCount("/quote") != 1 {
+		t.Errorf("Expected finnhub to be tried first, got %d requests", fake.RequestCount("/quote"))
+	}
+
+	stats := server.failover.Stats()
+	if len(stats) != 2 || stats[0].Name != "finnhub" || stats[0].Failures != 1 || stats[1].Served != 1 {
+		t.Errorf("Unexpected source stats %+v", stats)
+	}
+}
+
+func TestReplayStepMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	recorder, err := recording.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, price := range []string{"100", "101", "102"} {
+		recorder.Record(time.Unix(1700000000+int64(i), 0), []byte(`{"type":"trade","data":[{"s":"AAPL","p":`+price+`,"t":1700000000000,"v":1}]}`))
+	}
+	recorder.Close()
+
+	t.Setenv("DATA_PROVIDER", "offline")
+	t.Setenv("OFFLINE_DATA_DIR", "../../fixtures")
+	t.Setenv("STREAM_SOURCE", "replay")
+	t.Setenv("REPLAY_PATH", path)
+	t.Setenv("REPLAY_MODE", "step")
+	t.Setenv("NEWS_POLL_SECONDS", "0")
+
+	server := setupServer("")
+	defer server.close()
+
+	r := gin.New()
+	server.setupRoutes(r)
+
+	post := func(url string) map[string]any {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", url, w.Code, w.Body.String())
+		}
+		var body map[string]any
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body
+	}
+
+	if body := post("/api/replay/step"); body["stepped"] != 1.0 || body["finished"] != false {
+		t.Errorf("Expected one frame, got %v", body)
+	}
+	if body := post("/api/replay/step?count=5"); body["stepped"] != 2.0 || body["finished"] != true {
+		t.Errorf("Expected the last two frames and the end of the log, got %v", body)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/replay/step?count=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for count 0, got %d", w.Code)
+	}
+}
+
+func TestServerCloseStopsReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	recorder, err := recording.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Record(time.Unix(1700000000, 0), []byte(`{"type":"ping"}`))
+	recorder.Record(time.Unix(1700003600, 0), []byte(`{"type":"ping"}`))
+	recorder.Close()
+
+	t.Setenv("DATA_PROVIDER", "offline")
+	t.Setenv("OFFLINE_DATA_DIR", "../../fixtures")
+	t.Setenv("STREAM_SOURCE", "replay")
+	t.Setenv("REPLAY_PATH", path)
+	t.Setenv("NEWS_POLL_SECONDS", "0")
+
+	server := setupServer("")
+	server.close()
+	server.close()
+
+	// Step only returns once the replayer is ready for it or has stopped,
+	// and a timed replay is never ready for it.
+	if server.replayer.Step() {
+		t.Error("Expected the replayer to be stopped")
+	}
+	select {
+	case <-server.done:
+	default:
+		t.Error("Expected the bar flush to be stopped")
+	}
+}
+
+func TestServerAgainstFakeFinnhub(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+	fake.SetQuote("AAPL", models.StockQuote{CurrentPrice: 261.74, Timestamp: float64(time.Now().Unix())})
+	fake.SetJSON("/news", "", []models.CompanyNews{})
+
+	// The first MSFT poll is the baseline; the story after it is new.
+	known := models.CompanyNews{Id: 1, Headline: "Microsoft ships", Url: "https://example.com/msft-ships"}
+	breaking := models.CompanyNews{Id: 2, Headline: "Microsoft breaks out", Url: "https://example.com/msft-breaks"}
+	fake.Handle("/company-news", "MSFT",
+		finnhubtest.Response{Body: []models.CompanyNews{known}},
+		finnhubtest.Response{Body: []models.CompanyNews{breaking, known}},
+	)
+
+	t.Setenv("FINNHUB_BASE_URL", fake.URL)
+	t.Setenv("FINNHUB_STREAM_URL", fake.StreamURL)
+	t.Setenv("ALLOWED_ORIGINS", "http://localhost:5173")
+	t.Setenv("SYMBOL_INDEX_DIR", t.TempDir())
+	t.Setenv("NEWS_POLL_SECONDS", "1")
+	recordPath := filepath.Join(t.TempDir(), "stream.log.gz")
+	t.Setenv("STREAM_RECORD_PATH", recordPath)
+
+	server := setupServer("fake-key")
+	defer server.close()
+
+	r := gin.New()
+	server.setupRoutes(r)
+
+	w := get(r, "/api/quote?symbol=AAPL")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "261.74") {
+		t.Fatalf("Expected the fake quote, got %d: %s", w.Code, w.Body.String())
+	}
+
+	httpServer := httptest.NewServer(r)
+	defer httpServer.Close()
+
+	header := http.Header{"Origin": {"http://localhost:5173"}}
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("Failed to connect to /ws: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]string{"type": "subscribe", "symbol": "MSFT"})
+	if !fake.WaitForSubscription("MSFT", 2*time.Second) {
+		t.Fatal("Expected the server to subscribe MSFT upstream")
+	}
+
+	fake.SendTrades(models.Trade{Price: 412.5, Symbol: "MSFT", Timestamp: time.Now().UnixMilli(), Volume: 10})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Did not receive the MSFT trade: %v", err)
+		}
+		if strings.Contains(string(msg), `"trade"`) && strings.Contains(string(msg), "412.5") {
+			break
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Did not receive the MSFT news: %v", err)
+		}
+
+		var message NewsMessage
+		if json.Unmarshal(msg, &message) != nil || message.Type != "news" {
+			continue
+		}
+		if message.Symbol != "MSFT" || len(message.Items) != 1 || message.Items[0].Id != 2 {
+			t.Fatalf("Expected only the breaking MSFT story, got %s", msg)
+		}
+		break
+	}
+	// Shutting down finishes the recording, so it reads back complete.
+	server.close()
+
+	file, err := os.Open(recordPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Expected a finished recording, got %v", err)
+	}
+	if !strings.Contains(string(data), "412.5") {
+		t.Error("Expected the MSFT trade in the recording")
+	}
+}
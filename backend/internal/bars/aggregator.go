@@ -0,0 +1,193 @@
+package bars
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+var DefaultIntervals = []time.Duration{time.Second, time.Minute, 5 * time.Minute, time.Hour}
+
+const DefaultHistory = 500
+
+type EventType string
+
+const (
+	EventUpdated EventType = "updated"
+	EventClosed  EventType = "closed"
+)
+
+type Event struct {
+	Type     EventType  `json:"event"`
+	Symbol   string     `json:"symbol"`
+	Interval string     `json:"interval"`
+	Bar      models.Bar `json:"bar"`
+}
+
+type seriesKey struct {
+	symbol   string
+	interval time.Duration
+}
+
+type series struct {
+	current *models.Bar
+	closed  []models.Bar
+}
+
+// Aggregator builds OHLCV bars per symbol and interval from live trades and
+// keeps the most recent closed bars of each series.
+type Aggregator struct {
+	intervals []time.Duration
+	history   int
+	series    map[seriesKey]*series
+	mu        sync.Mutex
+}
+
+func NewAggregator(intervals []time.Duration, history int) *Aggregator {
+	return &Aggregator{
+		intervals: intervals,
+		history:   history,
+		series:    make(map[seriesKey]*series),
+	}
+}
+
+func (a *Aggregator) Intervals() []time.Duration {
+	return a.intervals
+}
+
+// Add folds a trade into every interval and returns the resulting events: a
+// closed event when the trade starts a new bar, then an updated event for the
+// bar it landed in. Trades older than the current bar are ignored.
+func (a *Aggregator) Add(trade models.Trade) []Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var events []Event
+	for _, interval := range a.intervals {
+		key := seriesKey{symbol: trade.Symbol, interval: interval}
+		s, ok := a.series[key]
+		if !ok {
+			s = &series{}
+			a.series[key] = s
+		}
+
+		start := trade.Time().Truncate(interval)
+
+		if s.current != nil && start.Before(s.current.Time) {
+			continue
+		}
+		if s.current == nil && len(s.closed) > 0 && !start.After(s.closed[len(s.closed)-1].Time) {
+			continue
+		}
+
+		if s.current != nil && start.After(s.current.Time) {
+			events = append(events, a.close(key, s))
+		}
+
+		if s.current == nil {
+			s.current = &models.Bar{
+				Time:  start,
+				Open:  trade.Price,
+				High:  trade.Price,
+				Low:   trade.Price,
+				Close: trade.Price,
+			}
+		}
+
+		bar := s.current
+		bar.High = max(bar.High, trade.Price)
+		bar.Low = min(bar.Low, trade.Price)
+		bar.Close = trade.Price
+		bar.Volume += trade.Volume
+
+		events = append(events, Event{
+			Type:     EventUpdated,
+			Symbol:   trade.Symbol,
+			Interval: FormatInterval(interval),
+			Bar:      *bar,
+		})
+	}
+
+	return events
+}
+
+// Flush closes every bar whose period ended at or before now, so quiet
+// symbols still get their bars closed.
+func (a *Aggregator) Flush(now time.Time) []Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var events []Event
+	for key, s := range a.series {
+		if s.current != nil && !s.current.Time.Add(key.interval).After(now) {
+			events = append(events, a.close(key, s))
+		}
+	}
+	return events
+}
+
+// close must be called with a.mu held.
+func (a *Aggregator) close(key seriesKey, s *series) Event {
+	bar := *s.current
+	s.current = nil
+
+	s.closed = append(s.closed, bar)
+	if len(s.closed) > a.history {
+		s.closed = s.closed[len(s.closed)-a.history:]
+	}
+
+	return Event{
+		Type:     EventClosed,
+		Symbol:   key.symbol,
+		Interval: FormatInterval(key.interval),
+		Bar:      bar,
+	}
+}
+
+// Bars returns the in-progress bar, if any, and up to limit of the most
+// recent closed bars, oldest first.
+func (a *Aggregator) Bars(symbol string, interval time.Duration, limit int) (*models.Bar, []models.Bar) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.series[seriesKey{symbol: symbol, interval: interval}]
+	if !ok {
+		return nil, []models.Bar{}
+	}
+
+	var current *models.Bar
+	if s.current != nil {
+		bar := *s.current
+		current = &bar
+	}
+
+	closed := s.closed
+	if limit > 0 && len(closed) > limit {
+		closed = closed[len(closed)-limit:]
+	}
+
+	return current, append([]models.Bar{}, closed...)
+}
+
+func FormatInterval(interval time.Duration) string {
+	switch {
+	case interval%time.Hour == 0:
+		return fmt.Sprintf("%dh", interval/time.Hour)
+	case interval%time.Minute == 0:
+		return fmt.Sprintf("%dm", interval/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", interval/time.Second)
+	}
+}
+
+// ParseInterval accepts only the intervals the aggregator was built with.
+func (a *Aggregator) ParseInterval(s string) (time.Duration, error) {
+	for _, interval := range a.intervals {
+		if FormatInterval(interval) == s {
+			return interval, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported interval %q", s)
+}
@@ -0,0 +1,121 @@
+package bars
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+func trade(symbol string, price, volume float64, at time.Time) models.Trade {
+	return models.Trade{Symbol: symbol, Price: price, Volume: volume, Timestamp: at.UnixMilli()}
+}
+
+func TestAggregatorBuildsBars(t *testing.T) {
+	aggregator := NewAggregator([]time.Duration{time.Minute}, 10)
+	start := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+
+	aggregator.Add(trade("AAPL", 100, 1, start.Add(5*time.Second)))
+	aggregator.Add(trade("AAPL", 105, 2, start.Add(20*time.Second)))
+	aggregator.Add(trade("AAPL", 98, 3, start.Add(40*time.Second)))
+	events := aggregator.Add(trade("AAPL", 101, 4, start.Add(70*time.Second)))
+
+	if len(events) != 2 {
+		t.Fatalf("Expected closed and updated events, got %d", len(events))
+	}
+
+	closed := events[0]
+	if closed.Type != EventClosed || closed.Interval != "1m" || closed.Symbol != "AAPL" {
+		t.Errorf("Expected closed 1m AAPL event, got %+v", closed)
+	}
+
+	want := models.Bar{Time: start, Open: 100, High: 105, Low: 98, Close: 98, Volume: 6}
+	if closed.Bar != want {
+		t.Errorf("Expected bar %+v, got %+v", want, closed.Bar)
+	}
+
+	updated := events[1]
+	if updated.Type != EventUpdated || updated.Bar.Open != 101 || !updated.Bar.Time.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected new bar opening at 101, got %+v", updated)
+	}
+
+	current, history := aggregator.Bars("AAPL", time.Minute, 0)
+	if current == nil || current.Close != 101 {
+		t.Errorf("Expected current bar closing at 101, got %+v", current)
+	}
+	if len(history) != 1 || history[0] != want {
+		t.Errorf("Expected one closed bar in history, got %+v", history)
+	}
+}
+
+func TestAggregatorIgnoresLateTrades(t *testing.T) {
+	aggregator := NewAggregator([]time.Duration{time.Minute}, 10)
+	start := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+
+	aggregator.Add(trade("AAPL", 100, 1, start.Add(90*time.Second)))
+	events := aggregator.Add(trade("AAPL", 50, 1, start.Add(10*time.Second)))
+
+	if len(events) != 0 {
+		t.Errorf("Expected late trade to be ignored, got %+v", events)
+	}
+}
+
+func TestAggregatorFlush(t *testing.T) {
+	aggregator := NewAggregator([]time.Duration{time.Second, time.Minute}, 10)
+	start := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+
+	aggregator.Add(trade("AAPL", 100, 1, start))
+
+	events := aggregator.Flush(start.Add(2 * time.Second))
+	if len(events) != 1 || events[0].Interval != "1s" || events[0].Type != EventClosed {
+		t.Fatalf("Expected only the 1s bar to close, got %+v", events)
+	}
+
+	if events := aggregator.Flush(start.Add(2 * time.Second)); len(events) != 0 {
+		t.Errorf("Expected nothing left to flush, got %+v", events)
+	}
+
+	if events := aggregator.Add(trade("AAPL", 99, 1, start.Add(500*time.Millisecond))); len(events) != 1 {
+		t.Errorf("Expected only the 1m bar to take a trade for a flushed 1s bar, got %+v", events)
+	}
+}
+
+func TestAggregatorHistoryLimit(t *testing.T) {
+	aggregator := NewAggregator([]time.Duration{time.Second}, 3)
+	start := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+
+	for i := range 6 {
+		aggregator.Add(trade("AAPL", float64(100+i), 1, start.Add(time.Duration(i)*time.Second)))
+	}
+
+	_, history := aggregator.Bars("AAPL", time.Second, 0)
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 bars of history, got %d", len(history))
+	}
+	if history[0].Open != 102 {
+		t.Errorf("Expected oldest kept bar to open at 102, got %f", history[0].Open)
+	}
+
+	_, limited := aggregator.Bars("AAPL", time.Second, 2)
+	if len(limited) != 2 || limited[1].Open != 104 {
+		t.Errorf("Expected the 2 most recent bars, got %+v", limited)
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	aggregator := NewAggregator(DefaultIntervals, DefaultHistory)
+
+	for _, name := range []string{"1s", "1m", "5m", "1h"} {
+		interval, err := aggregator.ParseInterval(name)
+		if err != nil {
+			t.Errorf("Expected %s to parse, got %v", name, err)
+		}
+		if FormatInterval(interval) != name {
+			t.Errorf("Expected %s to round trip, got %s", name, FormatInterval(interval))
+		}
+	}
+
+	if _, err := aggregator.ParseInterval("15m"); err == nil {
+		t.Error("Expected unsupported interval to fail")
+	}
+}
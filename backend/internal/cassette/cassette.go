@@ -0,0 +1,268 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type Mode int
+
+const (
+	// Replay serves recorded responses and fails requests without one.
+	Replay Mode = iota
+	// Record sends requests upstream and stores the responses.
+	Record
+	// Passthrough sends requests upstream without touching the cassette.
+	Passthrough
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Replay:
+		return "replay"
+	case Record:
+		return "record"
+	case Passthrough:
+		return "passthrough"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "replay":
+		return Replay, nil
+	case "record":
+		return Record, nil
+	case "passthrough":
+		return Passthrough, nil
+	default:
+		return Replay, fmt.Errorf("unknown cassette mode %q", s)
+	}
+}
+
+// scrubbed lists query parameters and headers that must never reach a
+// cassette file.
+var (
+	scrubbedParams  = []string{"token"}
+	scrubbedHeaders = []string{"X-Finnhub-Token", "Set-Cookie", "Cookie", "Authorization"}
+)
+
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// MismatchError is returned in replay mode for a request the cassette has no
+// interaction for. Candidates are recorded requests for the same path.
+type MismatchError struct {
+	Cassette   string
+	Request    Request
+	Candidates []Request
+}
+
+func (e *MismatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cassette %s: no interaction for %s %s", e.Cassette, e.Request.Method, e.Request.URL)
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "\n\trecorded: %s %s", c.Method, c.URL)
+	}
+	return b.String()
+}
+
+// Transport is an http.RoundTripper that records responses to, or replays
+// them from, a JSON cassette file. Requests are matched on method and URL
+// with scrubbed parameters removed and the query sorted.
+type Transport struct {
+	Path string
+	Mode Mode
+	// Base sends requests upstream in Record and Passthrough mode;
+	// http.DefaultTransport when nil.
+	Base         http.RoundTripper
+	interactions []Interaction
+	mismatches   []*MismatchError
+	mu           sync.Mutex
+}
+
+// Open loads the cassette at path. A missing file is an empty cassette in
+// Record mode and an error in Replay mode.
+func Open(path string, mode Mode) (*Transport, error) {
+	t := &Transport{Path: path, Mode: mode}
+	if mode == Passthrough {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && mode == Record:
+		return t, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &t.interactions); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch t.Mode {
+	case Record:
+		return t.record(req)
+	case Passthrough:
+		return t.base().RoundTrip(req)
+	default:
+		return t.replay(req)
+	}
+}
+
+// Mismatches returns every request replay could not serve.
+func (t *Transport) Mismatches() []*MismatchError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*MismatchError(nil), t.mismatches...)
+}
+
+// Save writes recorded interactions back to Path. It does nothing outside
+// Record mode.
+func (t *Transport) Save() error {
+	if t.Mode != Record {
+		return nil
+	}
+
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.Path, append(data, '\n'), 0o644)
+}
+
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	key := requestKey(req)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, interaction := range t.interactions {
+		if interaction.Request == key {
+			return interaction.Response.toHTTP(req), nil
+		}
+	}
+
+	mismatch := &MismatchError{Cassette: t.Path, Request: key}
+	for _, interaction := range t.interactions {
+		if samePath(interaction.Request.URL, key.URL) {
+			mismatch.Candidates = append(mismatch.Candidates, interaction.Request)
+		}
+	}
+	t.mismatches = append(t.mismatches, mismatch)
+	return nil, mismatch
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := resp.Header.Clone()
+	for _, name := range scrubbedHeaders {
+		header.Del(name)
+	}
+
+	interaction := Interaction{
+		Request:  requestKey(req),
+		Response: Response{StatusCode: resp.StatusCode, Header: header, Body: string(body)},
+	}
+
+	t.mu.Lock()
+	replaced := false
+	for i := range t.interactions {
+		if t.interactions[i].Request == interaction.Request {
+			t.interactions[i] = interaction
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		t.interactions = append(t.interactions, interaction)
+	}
+	t.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func requestKey(req *http.Request) Request {
+	u := *req.URL
+	query := u.Query()
+	for _, name := range scrubbedParams {
+		query.Del(name)
+	}
+	// Encode sorts by key.
+	u.RawQuery = query.Encode()
+
+	return Request{Method: req.Method, URL: u.String()}
+}
+
+func samePath(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && ua.Host == ub.Host && ua.Path == ub.Path
+}
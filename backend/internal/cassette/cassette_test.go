@@ -0,0 +1,115 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordScrubsTokenAndReplays(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(`{"c":150.25,"symbol":"` + r.URL.Query().Get("symbol") + `"}`))
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "quote.json")
+
+	recorder, err := Open(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: recorder}
+	resp, err := client.Get(upstream.URL + "/quote?symbol=AAPL&token=super-secret")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "150.25") {
+		t.Errorf("Expected upstream body, got %s", body)
+	}
+
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(saved), "super-secret") || strings.Contains(string(saved), "session=secret") {
+		t.Errorf("Expected credentials to be scrubbed, got %s", saved)
+	}
+
+	upstream.Close()
+
+	replayer, err := Open(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = &http.Client{Transport: replayer}
+	resp, err = client.Get(upstream.URL + "/quote?token=other-key&symbol=AAPL")
+	if err != nil {
+		t.Fatalf("Expected replayed response, got %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "150.25") {
+		t.Errorf("Expected replayed body, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestReplayReportsMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quote.json")
+	os.WriteFile(path, []byte(`[{
+		"request": {"method": "GET", "url": "https://finnhub.io/api/v1/quote?symbol=AAPL"},
+		"response": {"status": 200, "body": "{}"}
+	}]`), 0o644)
+
+	replayer, err := Open(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: replayer}
+	_, err = client.Get("https://finnhub.io/api/v1/quote?symbol=MSFT&token=x")
+
+	var mismatch *MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected MismatchError, got %v", err)
+	}
+	if len(mismatch.Candidates) != 1 || !strings.Contains(mismatch.Error(), "symbol=AAPL") {
+		t.Errorf("Expected the AAPL interaction as candidate, got %v", mismatch)
+	}
+	if len(replayer.Mismatches()) != 1 {
+		t.Errorf("Expected 1 recorded mismatch, got %d", len(replayer.Mismatches()))
+	}
+}
+
+func TestOpenMissingCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+
+	if _, err := Open(path, Replay); err == nil {
+		t.Error("Expected error opening a missing cassette for replay")
+	}
+	if _, err := Open(path, Record); err != nil {
+		t.Errorf("Expected a new cassette in record mode, got %v", err)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode("passthrough"); err != nil || mode != Passthrough {
+		t.Errorf("Expected Passthrough, got %v, %v", mode, err)
+	}
+	if _, err := ParseMode("rewind"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,402 @@
+package finnhub
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+	"github.com/rinz5/co-finance/backend/internal/provider"
+)
+
+const DefaultCacheSize = 1000
+
+type CacheTTLs struct {
+	Quote           time.Duration
+	Financials      time.Duration
+	Earnings        time.Duration
+	Recommendations time.Duration
+	Insiders        time.Duration
+	News            time.Duration
+	MarketStatus    time.Duration
+	Candles         time.Duration
+	Profile         time.Duration
+	Peers           time.Duration
+	Search          time.Duration
+	Calendar        time.Duration
+}
+
+func DefaultCacheTTLs() CacheTTLs {
+	return CacheTTLs{
+		Quote:           5 * time.Second,
+		Financials:      6 * time.Hour,
+		Earnings:        6 * time.Hour,
+		Recommendations: 6 * time.Hour,
+		Insiders:        time.Hour,
+		News:            5 * time.Minute,
+		MarketStatus:    time.Minute,
+		Candles:         time.Minute,
+		Profile:         24 * time.Hour,
+		Peers:           24 * time.Hour,
+		Search:          time.Hour,
+		Calendar:        time.Hour,
+	}
+}
+
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Coalesced uint64 `json:"coalesced"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// CachedClient wraps a provider, usually a Client, with a TTL cache bounded by
+// LRU eviction. Concurrent misses for the same request share a single
+// upstream call.
+type CachedClient struct {
+	client    provider.MarketDataProvider
+	ttls      CacheTTLs
+	cache     *lruCache
+	group     singleflight.Group
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	coalesced atomic.Uint64
+}
+
+func NewCachedClient(client provider.MarketDataProvider, ttls CacheTTLs, maxEntries int) *CachedClient {
+	return &CachedClient{
+		client: client,
+		ttls:   ttls,
+		cache:  newLRUCache(maxEntries),
+	}
+}
+
+func (c *CachedClient) Stats() CacheStats {
+	entries, evictions := c.cache.stats()
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Evictions: evictions,
+		Entries:   entries,
+	}
+}
+
+func (c *CachedClient) GetQuote(symbol string) (*models.StockQuote, error) {
+	return c.GetQuoteContext(context.Background(), symbol)
+}
+
+func (c *CachedClient) GetQuoteContext(ctx context.Context, symbol string) (*models.StockQuote, error) {
+	return cached(ctx, c, "quote:"+symbol, c.ttls.Quote, func() (*models.StockQuote, error) {
+		return c.client.GetQuoteContext(ctx, symbol)
+	})
+}
+
+func (c *CachedClient) GetBasicFinancials(symbol string) (*models.BasicFinancials, error) {
+	return c.GetBasicFinancialsContext(context.Background(), symbol)
+}
+
+func (c *CachedClient) GetBasicFinancialsContext(ctx context.Context, symbol string) (*models.BasicFinancials, error) {
+	return cached(ctx, c, "financials:"+symbol, c.ttls.Financials, func() (*models.BasicFinancials, error) {
+		return c.client.GetBasicFinancialsContext(ctx, symbol)
+	})
+}
+
+func (c *CachedClient) GetEarnings(symbol string) ([]models.EarningsSurprise, error) {
+	return c.GetEarningsContext(context.Background(), symbol)
+}
+
+func (c *CachedClient) GetEarningsContext(ctx context.Context, symbol string) ([]models.EarningsSurprise, error) {
+	return cached(ctx, c, "earnings:"+symbol, c.ttls.Earnings, func() ([]models.EarningsSurprise, error) {
+		return c.client.GetEarningsContext(ctx, symbol)
+	})
+}
+
+func (c *CachedClient) GetRecommendations(symbol string) ([]models.RecommendationTrend, error) {
+	return c.GetRecommendationsContext(context.Background(), symbol)
+}
+
+func (c *CachedClient) GetRecommendationsContext(ctx context.Context, symbol string) ([]models.RecommendationTrend, error) {
+	return cached(ctx, c, "recommendations:"+symbol, c.ttls.Recommendations, func() ([]models.RecommendationTrend, error) {
+		return c.client.GetRecommendationsContext(ctx, symbol)
+	})
+}
+
+func (c *CachedClient) GetInsiderTransactions(symbol string) ([]models.InsiderTransaction, error) {
+	return c.GetInsiderTransactionsContext(context.Background(), symbol)
+}
+
+func (c *CachedClient) GetInsiderTransactionsContext(ctx context.Context, symbol string) ([]models.InsiderTransaction, error) {
+	return cached(ctx, c, "insiders:"+symbol, c.ttls.Insiders, func() ([]models.InsiderTransaction, error) {
+		return c.client.GetInsiderTransactionsContext(ctx, symbol)
+	})
+}
+
+func (c *CachedClient) GetCompanyNews(symbol, from, to string) ([]models.CompanyNews, error) {
+	return c.GetCompanyNewsContext(context.Background(), symbol, from, to)
+}
+
+func (c *CachedClient) GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error) {
+	key := "news:" + symbol + ":" + from + ":" + to
+	return cached(ctx, c, key, c.ttls.News, func() ([]models.CompanyNews, error) {
+		return c.client.GetCompanyNewsContext(ctx, symbol, from, to)
+	})
+}
+
+func (c *CachedClient) GetMarketNews(category string, minID int64) ([]models.CompanyNews, error) {
+	return c.GetMarketNewsContext(context.Background(), category, minID)
+}
+
+func (c *CachedClient) GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error) {
+	key := fmt.Sprintf("market-news:%s:%d", category, minID)
+	return cached(ctx, c, key, c.ttls.News, func() ([]models.CompanyNews, error) {
+		return c.client.GetMarketNewsContext(ctx, category, minID)
+	})
+}
+
+func (c *CachedClient) GetMarketStatus(market string) (*models.MarketStatus, error) {
+	return c.GetMarketStatusContext(context.Background(), market)
+}
+
+func (c *CachedClient) GetMarketStatusContext(ctx context.Context, market string) (*models.MarketStatus, error) {
+	return cached(ctx, c, "market-status:"+market, c.ttls.MarketStatus, func() (*models.MarketStatus, error) {
+		return c.client.GetMarketStatusContext(ctx, market)
+	})
+}
+
+func (c *CachedClient) GetCandles(symbol, resolution string, from, to time.Time) (*models.Candles, error) {
+	return c.GetCandlesContext(context.Background(), symbol, resolution, from, to)
+}
+
+// GetCandlesContext widens from and to to whole bars, so requests that
+// differ by less than one bar, like repeated ones ending now, share an entry.
+// to is rounded up, so the bar it falls in, e.g. today's, is still included.
+func (c *CachedClient) GetCandlesContext(ctx context.Context, symbol, resolution string, from, to time.Time) (*models.Candles, error) {
+	width := candleWidth(resolution)
+	from = from.Truncate(width)
+	if aligned := to.Truncate(width); !aligned.Equal(to) {
+		to = aligned.Add(width)
+	}
+
+	key := fmt.Sprintf("candles:%s:%s:%d:%d", symbol, resolution, from.Unix(), to.Unix())
+	return cached(ctx, c, key, c.ttls.Candles, func() (*models.Candles, error) {
+		return c.client.GetCandlesContext(ctx, symbol, resolution, from, to)
+	})
+}
+
+// candleWidth is the bar width of an intraday resolution, given in minutes.
+// Daily and longer resolutions are aligned to the day.
+func candleWidth(resolution string) time.Duration {
+	if minutes, err := strconv.Atoi(resolution); err == nil && minutes > 0 {
+		return min(time.Duration(minutes)*time.Minute, 24*time.Hour)
+	}
+	return 24 * time.Hour
+}
+
+func (c *CachedClient) GetCompanyProfile(symbol string) (*models.CompanyProfile, error) {
+	return c.GetCompanyProfileContext(context.Background(), symbol)
+}
+
+func (c *CachedClient) GetCompanyProfileContext(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	return cached(ctx, c, "profile:"+symbol, c.ttls.Profile, func() (*models.CompanyProfile, error) {
+		return c.client.GetCompanyProfileContext(ctx, symbol)
+	})
+}
+
+func (c *CachedClient) GetPeers(symbol string) ([]string, error) {
+	return c.GetPeersContext(context.Background(), symbol)
+}
+
+func (c *CachedClient) GetPeersContext(ctx context.Context, symbol string) ([]string, error) {
+	return cached(ctx, c, "peers:"+symbol, c.ttls.Peers, func() ([]string, error) {
+		return c.client.GetPeersContext(ctx, symbol)
+	})
+}
+
+func (c *CachedClient) SearchSymbols(query string) (*models.SymbolLookup, error) {
+	return c.SearchSymbolsContext(context.Background(), query)
+}
+
+func (c *CachedClient) SearchSymbolsContext(ctx context.Context, query string) (*models.SymbolLookup, error) {
+	return cached(ctx, c, "search:"+query, c.ttls.Search, func() (*models.SymbolLookup, error) {
+		return c.client.SearchSymbolsContext(ctx, query)
+	})
+}
+
+func (c *CachedClient) GetStockSymbols(exchange string) ([]models.StockSymbol, error) {
+	return c.GetStockSymbolsContext(context.Background(), exchange)
+}
+
+// GetStockSymbolsContext is not cached: listings are several megabytes and
+// the symbol index already keeps them in memory and on disk.
+func (c *CachedClient) GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error) {
+	return c.client.GetStockSymbolsContext(ctx, exchange)
+}
+
+func (c *CachedClient) GetEarningsCalendar(from, to, symbol string) ([]models.EarningsRelease, error) {
+	return c.GetEarningsCalendarContext(context.Background(), from, to, symbol)
+}
+
+func (c *CachedClient) GetEarningsCalendarContext(ctx context.Context, from, to, symbol string) ([]models.EarningsRelease, error) {
+	key := fmt.Sprintf("calendar:earnings:%s:%s:%s", from, to, symbol)
+	return cached(ctx, c, key, c.ttls.Calendar, func() ([]models.EarningsRelease, error) {
+		return c.client.GetEarningsCalendarContext(ctx, from, to, symbol)
+	})
+}
+
+func (c *CachedClient) GetIPOCalendar(from, to string) ([]models.IPOEvent, error) {
+	return c.GetIPOCalendarContext(context.Background(), from, to)
+}
+
+func (c *CachedClient) GetIPOCalendarContext(ctx context.Context, from, to string) ([]models.IPOEvent, error) {
+	key := fmt.Sprintf("calendar:ipo:%s:%s", from, to)
+	return cached(ctx, c, key, c.ttls.Calendar, func() ([]models.IPOEvent, error) {
+		return c.client.GetIPOCalendarContext(ctx, from, to)
+	})
+}
+
+func (c *CachedClient) GetEconomicCalendar(from, to string) ([]models.EconomicEvent, error) {
+	return c.GetEconomicCalendarContext(context.Background(), from, to)
+}
+
+func (c *CachedClient) GetEconomicCalendarContext(ctx context.Context, from, to string) ([]models.EconomicEvent, error) {
+	key := fmt.Sprintf("calendar:economic:%s:%s", from, to)
+	return cached(ctx, c, key, c.ttls.Calendar, func() ([]models.EconomicEvent, error) {
+		return c.client.GetEconomicCalendarContext(ctx, from, to)
+	})
+}
+
+// cached serves key from the cache or loads it with fetch. Errors are never
+// cached. Callers share the returned value and must not modify it. A caller
+// whose ctx ends stops waiting; the shared upstream call runs with the ctx of
+// the caller that started it.
+func cached[T any](ctx context.Context, c *CachedClient, key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	var zero T
+
+	if value, ok := c.cache.get(key); ok {
+		c.hits.Add(1)
+		return value.(T), nil
+	}
+	c.misses.Add(1)
+
+	result := c.group.DoChan(key, func() (any, error) {
+		value, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		c.cache.set(key, value, ttl)
+		return value, nil
+	})
+
+	select {
+	case res := <-result:
+		if res.Shared {
+			c.coalesced.Add(1)
+		}
+
+		if res.Err != nil {
+			// The caller that started the shared request went away; retry
+			// on our own behalf.
+			if res.Shared && ctx.Err() == nil && errors.Is(res.Err, context.Canceled) {
+				value, err := fetch()
+				if err != nil {
+					return zero, err
+				}
+				c.cache.set(key, value, ttl)
+				return value, nil
+			}
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+type cacheEntry struct {
+	key     string
+	value   any
+	expires time.Time
+}
+
+type lruCache struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	evictions  uint64
+	mu         sync.Mutex
+}
+
+func newLRUCache(maxEntries int) *lruCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheSize
+	}
+
+	return &lruCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (l *lruCache) get(key string) (any, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		l.order.Remove(elem)
+		delete(l.entries, key)
+		return nil, false
+	}
+
+	l.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (l *lruCache) set(key string, value any, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+
+	if elem, ok := l.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = expires
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+
+	for l.order.Len() > l.maxEntries {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*cacheEntry).key)
+		l.evictions++
+	}
+}
+
+func (l *lruCache) stats() (int, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len(), l.evictions
+}
@@ -0,0 +1,247 @@
+package finnhub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedClientServesRepeatedCallsFromCache(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"c": 261.74}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	cachedClient := NewCachedClient(client, DefaultCacheTTLs(), 10)
+
+	for range 3 {
+		quote, err := cachedClient.GetQuote("AAPL")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if quote.CurrentPrice != 261.74 {
+			t.Errorf("Expected price 261.74, got %f", quote.CurrentPrice)
+		}
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", requests.Load())
+	}
+
+	stats := cachedClient.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Expected 2 hits, 1 miss and 1 entry, got %+v", stats)
+	}
+}
+
+func TestCachedClientAlignsCandleRanges(t *testing.T) {
+	var mu sync.Mutex
+	var ranges [][2]string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, [2]string{r.URL.Query().Get("from"), r.URL.Query().Get("to")})
+		mu.Unlock()
+
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		if to <= from {
+			w.Write([]byte(`{"s":"no_data"}`))
+			return
+		}
+		w.Write([]byte(`{"s":"ok","t":[1704196800],"o":[1],"h":[1],"l":[1],"c":[1],"v":[1]}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	cachedClient := NewCachedClient(client, DefaultCacheTTLs(), 10)
+
+	from := time.Date(2024, 1, 2, 9, 31, 10, 0, time.UTC)
+	to := time.Date(2024, 1, 2, 15, 57, 40, 0, time.UTC)
+	for _, offset := range []time.Duration{0, 20 * time.Second, 2 * time.Minute} {
+		if _, err := cachedClient.GetCandles("AAPL", "5", from.Add(offset), to.Add(offset)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if len(ranges) != 1 {
+		t.Fatalf("Expected requests within one bar to share an entry, got %d upstream requests", len(ranges))
+	}
+	if ranges[0] != [2]string{"1704187800", "1704211200"} {
+		t.Errorf("Expected from and to widened to 5 minute bars, got %v", ranges[0])
+	}
+
+	if _, err := cachedClient.GetCandles("AAPL", "D", from, to.Add(time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := cachedClient.GetCandles("AAPL", "D", from, to.Add(2*time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(ranges) != 2 {
+		t.Fatalf("Expected daily candles on the same day to share an entry, got %d upstream requests", len(ranges))
+	}
+	if ranges[1] != [2]string{"1704153600", "1704240000"} {
+		t.Errorf("Expected the range to cover the whole day, got %v", ranges[1])
+	}
+
+	// A range that starts and ends today must still include today's bar.
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	if _, err := cachedClient.GetCandles("MSFT", "D", today, now); err != nil {
+		t.Fatalf("Expected today's bar, got %v", err)
+	}
+	want := [2]string{strconv.FormatInt(today.Unix(), 10), strconv.FormatInt(today.Add(24*time.Hour).Unix(), 10)}
+	if ranges[2] != want {
+		t.Errorf("Expected %v, got %v", want, ranges[2])
+	}
+}
+
+func TestCachedClientDoesNotCacheListings(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"symbol":"AAPL"}]`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	cachedClient := NewCachedClient(client, DefaultCacheTTLs(), 10)
+
+	for range 2 {
+		if _, err := cachedClient.GetStockSymbols("US"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if requests.Load() != 2 || cachedClient.Stats().Entries != 0 {
+		t.Errorf("Expected listings to bypass the cache, got %d requests and %+v", requests.Load(), cachedClient.Stats())
+	}
+}
+
+func TestCachedClientCoalescesConcurrentMisses(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"metric": {"beta": 1.2}, "symbol": "AAPL"}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	cachedClient := NewCachedClient(client, DefaultCacheTTLs(), 10)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cachedClient.GetBasicFinancials("AAPL"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if requests.Load() != 1 {
+		t.Errorf("Expected concurrent calls to share 1 upstream request, got %d", requests.Load())
+	}
+}
+
+func TestCachedClientDoesNotCacheErrors(t *testing.T) {
+	var requests atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	client.Retry = RetryPolicy{MaxAttempts: 1}
+	cachedClient := NewCachedClient(client, DefaultCacheTTLs(), 10)
+
+	cachedClient.GetQuote("AAPL")
+	cachedClient.GetQuote("AAPL")
+
+	if requests.Load() != 2 {
+		t.Errorf("Expected errors not to be cached, got %d upstream requests", requests.Load())
+	}
+}
+
+func TestLRUCacheExpiryAndEviction(t *testing.T) {
+	cache := newLRUCache(2)
+
+	cache.set("a", 1, time.Hour)
+	cache.set("b", 2, time.Hour)
+	cache.get("a")
+	cache.set("c", 3, time.Hour)
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("Expected least recently used entry b to be evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("Expected recently used entry a to be kept")
+	}
+
+	cache.set("d", 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.get("d"); ok {
+		t.Error("Expected expired entry d to be missing")
+	}
+
+	if entries, evictions := cache.stats(); entries != 1 || evictions != 2 {
+		t.Errorf("Expected 1 entry and 2 evictions, got %d and %d", entries, evictions)
+	}
+}
+
+func TestCachedClientCallerCancellation(t *testing.T) {
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"c": 261.74}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	cachedClient := NewCachedClient(client, DefaultCacheTTLs(), 10)
+
+	leader := make(chan error, 1)
+	go func() {
+		_, err := cachedClient.GetQuoteContext(context.Background(), "AAPL")
+		leader <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := cachedClient.GetQuoteContext(ctx, "AAPL"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected waiting caller to give up with its own context, got %v", err)
+	}
+
+	close(release)
+	if err := <-leader; err != nil {
+		t.Errorf("Expected leading caller to succeed, got %v", err)
+	}
+}
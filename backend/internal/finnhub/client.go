@@ -1,20 +1,46 @@
 package finnhub
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/rinz5/co-finance/backend/internal/models"
+	"github.com/rinz5/co-finance/backend/internal/provider"
 )
 
-const DefaultBaseURL = "https://finnhub.io/api/v1"
+const (
+	DefaultBaseURL = "https://finnhub.io/api/v1"
+
+	maxResponseSize = 10 << 20
+
+	// The US symbol listing alone is several megabytes.
+	maxSymbolListSize = 32 << 20
+)
+
+var (
+	_ provider.MarketDataProvider = (*Client)(nil)
+	_ provider.MarketDataProvider = (*CachedClient)(nil)
+)
 
 type Client struct {
 	ApiKey     string
 	BaseURL    string
 	HTTPClient *http.Client
+	Limiter    *RateLimiter
+	Retry      RetryPolicy
+	requests   atomic.Uint64
+	attempts   atomic.Uint64
+	retries    atomic.Uint64
+	failures   atomic.Uint64
 }
 
 func NewClient(apiKey string) *Client {
@@ -24,45 +50,148 @@ func NewClient(apiKey string) *Client {
 		HTTPClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		Limiter: NewRateLimiter(DefaultRateLimits()),
+		Retry:   DefaultRetryPolicy(),
 	}
 }
 
-func (c *Client) GetQuote(symbol string) (*models.StockQuote, error) {
-	url := fmt.Sprintf("%s/quote?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
+func (c *Client) RetryStats() RetryStats {
+	return RetryStats{
+		Requests: c.requests.Load(),
+		Attempts: c.attempts.Load(),
+		Retries:  c.retries.Load(),
+		Failures: c.failures.Load(),
+	}
+}
+
+// get fetches url and decodes the JSON body into out, retrying transient
+// failures according to c.Retry.
+func (c *Client) get(ctx context.Context, url string, out any) error {
+	return c.getLimited(ctx, url, out, maxResponseSize)
+}
+
+// getLimited is get for responses of up to limit bytes.
+func (c *Client) getLimited(ctx context.Context, url string, out any, limit int64) error {
+	c.requests.Add(1)
+
+	attempts := max(c.Retry.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		c.attempts.Add(1)
+
+		err = c.attempt(ctx, url, out, limit)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == attempts || !retryable(err) {
+			break
+		}
+
+		delay, ok := c.Retry.delay(attempt-1, err)
+		if !ok {
+			break
+		}
+		log.Printf("Finnhub request %s failed (attempt %d/%d), retrying in %s: %v", redactToken(url), attempt, attempts, delay, err)
+		c.retries.Add(1)
+
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	c.failures.Add(1)
+	return err
+}
+
+// attempt waits for the rate limiter and performs a single request.
+func (c *Client) attempt(ctx context.Context, url string, out any, limit int64) error {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
 
-	resp, err := c.HTTPClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return redactURLError(err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return redactURLError(err)
 	}
 	defer resp.Body.Close()
 
+	if c.Limiter != nil {
+		c.Limiter.Observe(resp.StatusCode, resp.Header)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return err
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
+		return newStatusError(resp, body)
 	}
 
-	var quote models.StockQuote
-	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
-		return nil, err
+	if err := json.Unmarshal(body, out); err != nil {
+		return &APIError{Kind: ErrDecode, StatusCode: resp.StatusCode, Body: snippet(body), Err: err}
 	}
 
-	return &quote, nil
+	return nil
 }
 
-func (c *Client) GetBasicFinancials(symbol string) (*models.BasicFinancials, error) {
-	url := fmt.Sprintf("%s/stock/metric?symbol=%s&metric=all&token=%s", c.BaseURL, symbol, c.ApiKey)
+// redactURLError strips the API key from the URL net/http puts in its
+// errors, since those end up in logs.
+func redactURLError(err error) error {
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		redacted := *urlErr
+		redacted.URL = redactToken(urlErr.URL)
+		return &redacted
+	}
+	return err
+}
 
-	resp, err := c.HTTPClient.Get(url)
-	if err != nil {
+func redactToken(rawURL string) string {
+	if i := strings.Index(rawURL, "token="); i >= 0 {
+		return rawURL[:i] + "token=REDACTED"
+	}
+	return rawURL
+}
+
+func (c *Client) GetQuote(symbol string) (*models.StockQuote, error) {
+	return c.GetQuoteContext(context.Background(), symbol)
+}
+
+func (c *Client) GetQuoteContext(ctx context.Context, symbol string) (*models.StockQuote, error) {
+	url := fmt.Sprintf("%s/quote?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
+
+	var quote models.StockQuote
+	if err := c.get(ctx, url, &quote); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
+	// Finnhub answers unknown symbols with 200 and an all-zero quote.
+	if quote.Timestamp == 0 && quote.CurrentPrice == 0 {
+		return nil, &APIError{Kind: ErrNotFound, StatusCode: http.StatusOK, Body: "unknown symbol " + symbol}
 	}
 
+	return &quote, nil
+}
+
+func (c *Client) GetBasicFinancials(symbol string) (*models.BasicFinancials, error) {
+	return c.GetBasicFinancialsContext(context.Background(), symbol)
+}
+
+func (c *Client) GetBasicFinancialsContext(ctx context.Context, symbol string) (*models.BasicFinancials, error) {
+	url := fmt.Sprintf("%s/stock/metric?symbol=%s&metric=all&token=%s", c.BaseURL, symbol, c.ApiKey)
+
 	var financials models.BasicFinancials
-	if err := json.NewDecoder(resp.Body).Decode(&financials); err != nil {
+	if err := c.get(ctx, url, &financials); err != nil {
 		return nil, err
 	}
 
@@ -70,20 +199,14 @@ func (c *Client) GetBasicFinancials(symbol string) (*models.BasicFinancials, err
 }
 
 func (c *Client) GetEarnings(symbol string) ([]models.EarningsSurprise, error) {
-	url := fmt.Sprintf("%s/stock/earnings?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
-
-	resp, err := c.HTTPClient.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetEarningsContext(context.Background(), symbol)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
-	}
+func (c *Client) GetEarningsContext(ctx context.Context, symbol string) ([]models.EarningsSurprise, error) {
+	url := fmt.Sprintf("%s/stock/earnings?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
 
 	var earnings []models.EarningsSurprise
-	if err := json.NewDecoder(resp.Body).Decode(&earnings); err != nil {
+	if err := c.get(ctx, url, &earnings); err != nil {
 		return nil, err
 	}
 
@@ -91,20 +214,14 @@ func (c *Client) GetEarnings(symbol string) ([]models.EarningsSurprise, error) {
 }
 
 func (c *Client) GetRecommendations(symbol string) ([]models.RecommendationTrend, error) {
-	url := fmt.Sprintf("%s/stock/recommendation?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
-
-	resp, err := c.HTTPClient.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetRecommendationsContext(context.Background(), symbol)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
-	}
+func (c *Client) GetRecommendationsContext(ctx context.Context, symbol string) ([]models.RecommendationTrend, error) {
+	url := fmt.Sprintf("%s/stock/recommendation?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
 
 	var recommendations []models.RecommendationTrend
-	if err := json.NewDecoder(resp.Body).Decode(&recommendations); err != nil {
+	if err := c.get(ctx, url, &recommendations); err != nil {
 		return nil, err
 	}
 
@@ -112,23 +229,17 @@ func (c *Client) GetRecommendations(symbol string) ([]models.RecommendationTrend
 }
 
 func (c *Client) GetInsiderTransactions(symbol string) ([]models.InsiderTransaction, error) {
-	url := fmt.Sprintf("%s/stock/insider-transactions?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
-
-	resp, err := c.HTTPClient.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return c.GetInsiderTransactionsContext(context.Background(), symbol)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
-	}
+func (c *Client) GetInsiderTransactionsContext(ctx context.Context, symbol string) ([]models.InsiderTransaction, error) {
+	url := fmt.Sprintf("%s/stock/insider-transactions?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
 
 	var wrapper struct {
 		Data []models.InsiderTransaction `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
+	if err := c.get(ctx, url, &wrapper); err != nil {
 		return nil, err
 	}
 
@@ -136,20 +247,32 @@ func (c *Client) GetInsiderTransactions(symbol string) ([]models.InsiderTransact
 }
 
 func (c *Client) GetCompanyNews(symbol, from, to string) ([]models.CompanyNews, error) {
+	return c.GetCompanyNewsContext(context.Background(), symbol, from, to)
+}
+
+func (c *Client) GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error) {
 	url := fmt.Sprintf("%s/company-news?symbol=%s&from=%s&to=%s&token=%s", c.BaseURL, symbol, from, to, c.ApiKey)
 
-	resp, err := c.HTTPClient.Get(url)
-	if err != nil {
+	var news []models.CompanyNews
+	if err := c.get(ctx, url, &news); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
-	}
+	return news, nil
+}
+
+func (c *Client) GetMarketNews(category string, minID int64) ([]models.CompanyNews, error) {
+	return c.GetMarketNewsContext(context.Background(), category, minID)
+}
+
+// GetMarketNewsContext returns the latest general news in category (general,
+// forex, crypto or merger). A non-zero minID limits it to stories with a
+// higher id.
+func (c *Client) GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error) {
+	url := fmt.Sprintf("%s/news?category=%s&minId=%d&token=%s", c.BaseURL, category, minID, c.ApiKey)
 
 	var news []models.CompanyNews
-	if err := json.NewDecoder(resp.Body).Decode(&news); err != nil {
+	if err := c.get(ctx, url, &news); err != nil {
 		return nil, err
 	}
 
@@ -157,22 +280,153 @@ func (c *Client) GetCompanyNews(symbol, from, to string) ([]models.CompanyNews,
 }
 
 func (c *Client) GetMarketStatus(market string) (*models.MarketStatus, error) {
+	return c.GetMarketStatusContext(context.Background(), market)
+}
+
+func (c *Client) GetMarketStatusContext(ctx context.Context, market string) (*models.MarketStatus, error) {
 	url := fmt.Sprintf("%s/stock/market-status?exchange=%s&token=%s", c.BaseURL, market, c.ApiKey)
 
-	resp, err := c.HTTPClient.Get(url)
-	if err != nil {
+	var marketStatus models.MarketStatus
+	if err := c.get(ctx, url, &marketStatus); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error: status %d", resp.StatusCode)
+	return &marketStatus, nil
+}
+
+func (c *Client) GetCandles(symbol, resolution string, from, to time.Time) (*models.Candles, error) {
+	return c.GetCandlesContext(context.Background(), symbol, resolution, from, to)
+}
+
+func (c *Client) GetCandlesContext(ctx context.Context, symbol, resolution string, from, to time.Time) (*models.Candles, error) {
+	url := fmt.Sprintf("%s/stock/candle?symbol=%s&resolution=%s&from=%d&to=%d&token=%s",
+		c.BaseURL, symbol, resolution, from.Unix(), to.Unix(), c.ApiKey)
+
+	var candles models.Candles
+	if err := c.get(ctx, url, &candles); err != nil {
+		return nil, err
 	}
 
-	var marketStatus models.MarketStatus
-	if err := json.NewDecoder(resp.Body).Decode(&marketStatus); err != nil {
+	// Finnhub answers ranges without trades with 200 and {"s":"no_data"}.
+	if candles.Status == "no_data" {
+		return nil, &APIError{Kind: ErrNoData, StatusCode: http.StatusOK, Body: "no candles for " + symbol}
+	}
+
+	return &candles, nil
+}
+
+func (c *Client) GetCompanyProfile(symbol string) (*models.CompanyProfile, error) {
+	return c.GetCompanyProfileContext(context.Background(), symbol)
+}
+
+func (c *Client) GetCompanyProfileContext(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	url := fmt.Sprintf("%s/stock/profile2?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
+
+	var profile models.CompanyProfile
+	if err := c.get(ctx, url, &profile); err != nil {
 		return nil, err
 	}
 
-	return &marketStatus, nil
+	// Finnhub answers unknown symbols with 200 and an empty object.
+	if profile.Ticker == "" && profile.Name == "" {
+		return nil, &APIError{Kind: ErrNotFound, StatusCode: http.StatusOK, Body: "no profile for " + symbol}
+	}
+
+	return &profile, nil
+}
+
+func (c *Client) GetPeers(symbol string) ([]string, error) {
+	return c.GetPeersContext(context.Background(), symbol)
+}
+
+func (c *Client) GetPeersContext(ctx context.Context, symbol string) ([]string, error) {
+	url := fmt.Sprintf("%s/stock/peers?symbol=%s&token=%s", c.BaseURL, symbol, c.ApiKey)
+
+	var peers []string
+	if err := c.get(ctx, url, &peers); err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+func (c *Client) SearchSymbols(query string) (*models.SymbolLookup, error) {
+	return c.SearchSymbolsContext(context.Background(), query)
+}
+
+func (c *Client) SearchSymbolsContext(ctx context.Context, query string) (*models.SymbolLookup, error) {
+	url := fmt.Sprintf("%s/search?q=%s&token=%s", c.BaseURL, neturl.QueryEscape(query), c.ApiKey)
+
+	var lookup models.SymbolLookup
+	if err := c.get(ctx, url, &lookup); err != nil {
+		return nil, err
+	}
+
+	return &lookup, nil
+}
+
+func (c *Client) GetStockSymbols(exchange string) ([]models.StockSymbol, error) {
+	return c.GetStockSymbolsContext(context.Background(), exchange)
+}
+
+func (c *Client) GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error) {
+	url := fmt.Sprintf("%s/stock/symbol?exchange=%s&token=%s", c.BaseURL, exchange, c.ApiKey)
+
+	var symbols []models.StockSymbol
+	if err := c.getLimited(ctx, url, &symbols, maxSymbolListSize); err != nil {
+		return nil, err
+	}
+
+	return symbols, nil
+}
+
+func (c *Client) GetEarningsCalendar(from, to, symbol string) ([]models.EarningsRelease, error) {
+	return c.GetEarningsCalendarContext(context.Background(), from, to, symbol)
+}
+
+// GetEarningsCalendarContext returns releases between from and to
+// (YYYY-MM-DD). An empty symbol covers every company.
+func (c *Client) GetEarningsCalendarContext(ctx context.Context, from, to, symbol string) ([]models.EarningsRelease, error) {
+	filter := ""
+	if symbol != "" {
+		filter = "&symbol=" + symbol
+	}
+	url := fmt.Sprintf("%s/calendar/earnings?from=%s&to=%s%s&token=%s", c.BaseURL, from, to, filter, c.ApiKey)
+
+	var calendar models.EarningsCalendar
+	if err := c.get(ctx, url, &calendar); err != nil {
+		return nil, err
+	}
+
+	return calendar.EarningsCalendar, nil
+}
+
+func (c *Client) GetIPOCalendar(from, to string) ([]models.IPOEvent, error) {
+	return c.GetIPOCalendarContext(context.Background(), from, to)
+}
+
+func (c *Client) GetIPOCalendarContext(ctx context.Context, from, to string) ([]models.IPOEvent, error) {
+	url := fmt.Sprintf("%s/calendar/ipo?from=%s&to=%s&token=%s", c.BaseURL, from, to, c.ApiKey)
+
+	var calendar models.IPOCalendar
+	if err := c.get(ctx, url, &calendar); err != nil {
+		return nil, err
+	}
+
+	return calendar.IPOCalendar, nil
+}
+
+func (c *Client) GetEconomicCalendar(from, to string) ([]models.EconomicEvent, error) {
+	return c.GetEconomicCalendarContext(context.Background(), from, to)
+}
+
+func (c *Client) GetEconomicCalendarContext(ctx context.Context, from, to string) ([]models.EconomicEvent, error) {
+	url := fmt.Sprintf("%s/calendar/economic?from=%s&to=%s&token=%s", c.BaseURL, from, to, c.ApiKey)
+
+	var calendar models.EconomicCalendar
+	if err := c.get(ctx, url, &calendar); err != nil {
+		return nil, err
+	}
+
+	return calendar.EconomicCalendar, nil
 }
@@ -1,11 +1,63 @@
 package finnhub
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/cassette"
 )
 
+// testdata/cassettes/client.json is a hand-written fixture modelled on
+// Finnhub's documented responses, not a recording. It is kept in the format
+// the recorder writes, so recording it for real with FINNHUB_API_KEY set and
+// go test ./internal/finnhub -run Cassette -cassette=record
+// only changes the data.
+var cassetteMode = flag.String("cassette", "replay", "cassette mode: replay, record or passthrough")
+
+// newCassetteClient returns a client talking to the real Finnhub API through
+// testdata/cassettes/<name>.json. Requests missing from the cassette fail the
+// test with a mismatch report.
+func newCassetteClient(t *testing.T, name string) *Client {
+	t.Helper()
+
+	mode, err := cassette.ParseMode(*cassetteMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport, err := cassette.Open(filepath.Join("testdata", "cassettes", name+".json"), mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		for _, mismatch := range transport.Mismatches() {
+			t.Error(mismatch)
+		}
+		if err := transport.Save(); err != nil {
+			t.Errorf("Failed to save cassette: %v", err)
+		}
+	})
+
+	apiKey := "fake-key"
+	if mode != cassette.Replay {
+		apiKey = os.Getenv("FINNHUB_API_KEY")
+	}
+
+	client := NewClient(apiKey)
+	client.HTTPClient = &http.Client{Transport: transport, Timeout: 10 * time.Second}
+	client.Retry = RetryPolicy{MaxAttempts: 1}
+	return client
+}
+
 func TestGetQuote(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/quote" {
@@ -75,15 +127,16 @@ func TestGetBasicFinancials(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	if financials.Metric.High52Week != 310.43 {
-		t.Errorf("Expected 52WeekHigh 310.43, got %f", financials.Metric.High52Week)
+	if financials.High52Week() != 310.43 {
+		t.Errorf("Expected 52WeekHigh 310.43, got %f", financials.High52Week())
 	}
 
-	if len(financials.Series.Annual.CurrentRatio) > 0 {
-		firstRatio := financials.Series.Annual.CurrentRatio[0].V
-		if firstRatio != 1.5401 {
-			t.Errorf("Expected first Current Ratio 1.5401, got %f", firstRatio)
-		}
+	if volume, ok := financials.Metric.Float("10DayAverageTradingVolume"); !ok || volume != 32.50 {
+		t.Errorf("Expected 10DayAverageTradingVolume 32.50, got %f", volume)
+	}
+
+	if ratios := financials.AnnualSeries("currentRatio"); len(ratios) != 2 || ratios[0].V != 1.5401 {
+		t.Errorf("Expected first Current Ratio 1.5401, got %v", ratios)
 	}
 
 	if financials.MetricType != "all" {
@@ -356,6 +409,32 @@ func TestGetCompanyNews(t *testing.T) {
 	}
 }
 
+func TestGetMarketNews(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/news" {
+			t.Errorf("Expected path /news, got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("category") != "forex" || r.URL.Query().Get("minId") != "7000" {
+			t.Errorf("Expected category forex and minId 7000, got %s", r.URL.RawQuery)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"category":"forex","datetime":1704196800,"headline":"Dollar slips","id":7001,"image":"","related":"","source":"Reuters","summary":"","url":"https://example.com/fx"}]`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+
+	news, err := client.GetMarketNews("forex", 7000)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(news) != 1 || news[0].Id != 7001 || news[0].Category != "forex" {
+		t.Errorf("Unexpected news %+v", news)
+	}
+}
+
 func TestGetMarketStatus(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/stock/market-status" {
@@ -407,3 +486,282 @@ func TestGetMarketStatus(t *testing.T) {
 		t.Errorf("Expected timestamp 1697018041, got %d", status.Timestamp)
 	}
 }
+
+func TestGetCandles(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stock/candle" {
+			t.Errorf("Expected path /stock/candle, got %s", r.URL.Path)
+		}
+
+		query := r.URL.Query()
+		if query.Get("resolution") != "D" || query.Get("from") != "1569297600" || query.Get("to") != "1569470400" {
+			t.Errorf("Expected resolution D from 1569297600 to 1569470400, got %s", r.URL.RawQuery)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		if query.Get("symbol") == "AAPL" {
+			w.Write([]byte(`{"c":[217.68,221.03],"h":[222.49,221.5],"l":[217.19,217.1402],"o":[221.03,218.55],"s":"ok","t":[1569297600,1569384000],"v":[33463820,24018876]}`))
+			return
+		}
+		w.Write([]byte(`{"s":"no_data"}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+
+	from, to := time.Unix(1569297600, 0), time.Unix(1569470400, 0)
+
+	candles, err := client.GetCandles("AAPL", "D", from, to)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if candles.Status != "ok" || len(candles.Close) != 2 || candles.Close[1] != 221.03 {
+		t.Errorf("Expected two ok candles, got %+v", candles)
+	}
+
+	if _, err := client.GetCandles("MSFT", "D", from, to); !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData, got %v", err)
+	}
+}
+
+func TestGetCompanyProfileAndPeers(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+
+		switch r.URL.Path + "?" + r.URL.Query().Get("symbol") {
+		case "/stock/profile2?AAPL":
+			w.Write([]byte(`{"country":"US","currency":"USD","exchange":"NASDAQ NMS - GLOBAL MARKET","finnhubIndustry":"Technology","ipo":"1980-12-12","logo":"https://static2.finnhub.io/file/publicdatany/finnhubimage/stock_logo/AAPL.png","marketCapitalization":2913283.5,"name":"Apple Inc","ticker":"AAPL"}`))
+		case "/stock/profile2?ZZZZZZ":
+			w.Write([]byte(`{}`))
+		case "/stock/peers?AAPL":
+			w.Write([]byte(`["AAPL","DELL","HPQ"]`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+
+	profile, err := client.GetCompanyProfile("AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if profile.Name != "Apple Inc" || profile.FinnhubIndustry != "Technology" || profile.Country != "US" {
+		t.Errorf("Unexpected profile %+v", profile)
+	}
+
+	if _, err := client.GetCompanyProfile("ZZZZZZ"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for empty profile, got %v", err)
+	}
+
+	peers, err := client.GetPeers("AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(peers) != 3 || peers[1] != "DELL" {
+		t.Errorf("Expected 3 peers, got %v", peers)
+	}
+}
+
+func TestSearchSymbolsAndListing(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+
+		switch r.URL.Path {
+		case "/search":
+			if r.URL.Query().Get("q") != "apple inc" {
+				t.Errorf("Expected query 'apple inc', got %q", r.URL.Query().Get("q"))
+			}
+			w.Write([]byte(`{"count":1,"result":[{"description":"APPLE INC","displaySymbol":"AAPL","symbol":"AAPL","type":"Common Stock"}]}`))
+		case "/stock/symbol":
+			if r.URL.Query().Get("exchange") != "US" {
+				t.Errorf("Expected exchange US, got %s", r.URL.Query().Get("exchange"))
+			}
+			w.Write([]byte(`[{"currency":"USD","description":"APPLE INC","displaySymbol":"AAPL","figi":"BBG000B9XRY4","mic":"XNAS","symbol":"AAPL","type":"Common Stock"}]`))
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+
+	lookup, err := client.SearchSymbols("apple inc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lookup.Count != 1 || lookup.Result[0].Symbol != "AAPL" {
+		t.Errorf("Unexpected lookup %+v", lookup)
+	}
+
+	listing, err := client.GetStockSymbols("US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(listing) != 1 || listing[0].Mic != "XNAS" {
+		t.Errorf("Unexpected listing %+v", listing)
+	}
+}
+
+func TestLargeResponseLimitOnlyForListings(t *testing.T) {
+	// A padded string field pushes the body past maxResponseSize.
+	padded := `{"symbol":"AAPL","description":"` + strings.Repeat("x", maxResponseSize) + `"}`
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Path == "/stock/symbol" {
+			w.Write([]byte("[" + padded + "]"))
+			return
+		}
+		w.Write([]byte(padded))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	client.Limiter = nil
+
+	listing, err := client.GetStockSymbols("US")
+	if err != nil {
+		t.Fatalf("Expected the listing to fit its larger limit, got %v", err)
+	}
+	if len(listing) != 1 || listing[0].Symbol != "AAPL" {
+		t.Errorf("Unexpected listing of %d symbols", len(listing))
+	}
+
+	if _, err := client.GetCompanyProfile("AAPL"); !errors.Is(err, ErrDecode) {
+		t.Errorf("Expected other endpoints to keep the default limit, got %v", err)
+	}
+}
+
+func TestGetCalendars(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("from") != "2024-01-01" || query.Get("to") != "2024-01-31" {
+			t.Errorf("Expected January range, got %s", r.URL.RawQuery)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/calendar/earnings":
+			if query.Has("symbol") {
+				t.Errorf("Expected no symbol filter, got %q", query.Get("symbol"))
+			}
+			w.Write([]byte(`{"earningsCalendar":[{"date":"2024-01-30","epsActual":null,"epsEstimate":2.78,"hour":"amc","quarter":2,"revenueActual":null,"revenueEstimate":61120000000,"symbol":"MSFT","year":2024}]}`))
+		case "/calendar/ipo":
+			w.Write([]byte(`{"ipoCalendar":[{"date":"2024-01-25","exchange":"NASDAQ Global","name":"CG ONCOLOGY INC","numberOfShares":11700000,"price":"19.00","status":"priced","symbol":"CGON","totalSharesValue":222300000}]}`))
+		case "/calendar/economic":
+			w.Write([]byte(`{"economicCalendar":[{"actual":3.4,"country":"US","estimate":3.2,"event":"CPI YoY","impact":"high","prev":3.1,"time":"2024-01-11 13:30:00","unit":"%"}]}`))
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+
+	releases, err := client.GetEarningsCalendar("2024-01-01", "2024-01-31", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(releases) != 1 || releases[0].EpsActual != nil || *releases[0].EpsEstimate != 2.78 {
+		t.Errorf("Unexpected earnings calendar %+v", releases)
+	}
+
+	ipos, err := client.GetIPOCalendar("2024-01-01", "2024-01-31")
+	if err != nil || len(ipos) != 1 || ipos[0].Symbol != "CGON" {
+		t.Errorf("Unexpected IPO calendar %+v, %v", ipos, err)
+	}
+
+	events, err := client.GetEconomicCalendar("2024-01-01", "2024-01-31")
+	if err != nil || len(events) != 1 || *events[0].Actual != 3.4 {
+		t.Errorf("Unexpected economic calendar %+v, %v", events, err)
+	}
+}
+
+func TestGetQuoteContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer mockServer.Close()
+	defer close(release)
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.GetQuoteContext(ctx, "AAPL")
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected request to abort promptly, took %s", elapsed)
+	}
+}
+
+func TestClientCassette(t *testing.T) {
+	client := newCassetteClient(t, "client")
+	ctx := context.Background()
+
+	quote, err := client.GetQuoteContext(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quote.CurrentPrice <= 0 || quote.Timestamp <= 0 {
+		t.Errorf("Expected a priced quote, got %+v", quote)
+	}
+
+	if _, err := client.GetQuoteContext(ctx, "ZZZZZZ"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown symbol, got %v", err)
+	}
+
+	financials, err := client.GetBasicFinancialsContext(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if financials.Symbol != "AAPL" || len(financials.AnnualSeries("currentRatio")) == 0 ||
+		len(financials.QuarterlySeries("currentRatio")) == 0 {
+		t.Errorf("Expected AAPL financials with annual and quarterly series, got %+v", financials)
+	}
+
+	earnings, err := client.GetEarningsContext(ctx, "AAPL")
+	if err != nil || len(earnings) == 0 {
+		t.Errorf("Expected earnings, got %d, %v", len(earnings), err)
+	}
+
+	trends, err := client.GetRecommendationsContext(ctx, "AAPL")
+	if err != nil || len(trends) == 0 {
+		t.Errorf("Expected recommendation trends, got %d, %v", len(trends), err)
+	}
+
+	insiders, err := client.GetInsiderTransactionsContext(ctx, "AAPL")
+	if err != nil || len(insiders) == 0 {
+		t.Errorf("Expected insider transactions, got %d, %v", len(insiders), err)
+	}
+
+	news, err := client.GetCompanyNewsContext(ctx, "AAPL", "2024-01-01", "2024-01-05")
+	if err != nil || len(news) == 0 {
+		t.Errorf("Expected company news, got %d, %v", len(news), err)
+	}
+
+	status, err := client.GetMarketStatusContext(ctx, "US")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.Exchange != "US" {
+		t.Errorf("Expected exchange US, got %s", status.Exchange)
+	}
+}
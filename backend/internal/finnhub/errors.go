@@ -0,0 +1,80 @@
+package finnhub
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/provider"
+)
+
+var (
+	ErrRateLimited         = errors.New("finnhub: rate limited")
+	ErrUnauthorized        = errors.New("finnhub: unauthorized")
+	ErrNotFound            = fmt.Errorf("finnhub: %w", provider.ErrNotFound)
+	ErrNoData              = fmt.Errorf("finnhub: %w", provider.ErrNoData)
+	ErrUpstreamUnavailable = errors.New("finnhub: upstream unavailable")
+	ErrUnexpectedStatus    = errors.New("finnhub: unexpected status")
+	ErrDecode              = errors.New("finnhub: decode failure")
+)
+
+const maxBodySnippet = 200
+
+// APIError describes a failed Finnhub request. Kind is one of the sentinel
+// errors above, so callers can match it with errors.Is.
+type APIError struct {
+	Kind       error
+	StatusCode int
+	RetryAfter time.Duration
+	Body       string
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%v (status %d)", e.Kind, e.StatusCode)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func newStatusError(resp *http.Response, body []byte) *APIError {
+	var kind error
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		kind = ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		kind = ErrNotFound
+	case resp.StatusCode >= 500:
+		kind = ErrUpstreamUnavailable
+	default:
+		kind = ErrUnexpectedStatus
+	}
+
+	return &APIError{
+		Kind:       kind,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Body:       snippet(body),
+	}
+}
+
+func snippet(body []byte) string {
+	if len(body) > maxBodySnippet {
+		return string(body[:maxBodySnippet]) + "..."
+	}
+	return string(body)
+}
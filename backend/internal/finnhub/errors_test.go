@@ -0,0 +1,85 @@
+package finnhub
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientErrorKinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		kind   error
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"error":"API limit reached"}`, ErrRateLimited},
+		{"unauthorized", http.StatusUnauthorized, `{"error":"Invalid API key"}`, ErrUnauthorized},
+		{"forbidden", http.StatusForbidden, `{"error":"You don't have access to this resource."}`, ErrUnauthorized},
+		{"not found", http.StatusNotFound, `not found`, ErrNotFound},
+		{"unavailable", http.StatusBadGateway, `<html>bad gateway</html>`, ErrUpstreamUnavailable},
+		{"unexpected", http.StatusTeapot, ``, ErrUnexpectedStatus},
+		{"decode", http.StatusOK, `{"c": "oops"`, ErrDecode},
+		{"unknown symbol", http.StatusOK, `{"c":0,"d":null,"dp":null,"h":0,"l":0,"o":0,"pc":0,"t":0}`, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer mockServer.Close()
+
+			client := NewClient("fake-key")
+			client.BaseURL = mockServer.URL
+			client.Limiter = nil
+			client.Retry = RetryPolicy{MaxAttempts: 1}
+
+			_, err := client.GetQuote("AAPL")
+
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("Expected %v, got %v", tt.kind, err)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected *APIError, got %T", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+		})
+	}
+}
+
+func TestClientErrorCarriesRetryAfterAndBody(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"API limit reached. Please try again later."}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewClient("fake-key")
+	client.BaseURL = mockServer.URL
+	client.Limiter = nil
+	client.Retry = RetryPolicy{MaxAttempts: 1}
+
+	_, err := client.GetEarnings("AAPL")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %v", err)
+	}
+
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("Expected retry after 7s, got %s", apiErr.RetryAfter)
+	}
+
+	if apiErr.Body != `{"error":"API limit reached. Please try again later."}` {
+		t.Errorf("Expected body snippet, got %q", apiErr.Body)
+	}
+}
@@ -0,0 +1,269 @@
+// Package finnhubtest provides a fake Finnhub for tests: a REST API with
+// programmable responses and a websocket trade stream, served from one
+// httptest server.
+package finnhubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+// Response is one programmed reply. Body is sent as is when it is a string or
+// []byte and JSON encoded otherwise. A zero Status means 200.
+type Response struct {
+	Status  int
+	Body    any
+	Header  http.Header
+	Latency time.Duration
+}
+
+// Request is a REST request the server received, without its token.
+type Request struct {
+	Path   string
+	Symbol string
+	Query  url.Values
+	Token  string
+}
+
+type route struct {
+	path   string
+	symbol string
+}
+
+// Server is a fake Finnhub. Point finnhub.Client.BaseURL at URL and
+// finnhub.StreamClient.BaseURL at StreamURL.
+type Server struct {
+	URL       string
+	StreamURL string
+	// Token, when set, is the only API key accepted; other requests get 401.
+	Token string
+	// Latency delays every REST response, on top of Response.Latency.
+	Latency time.Duration
+
+	server      *httptest.Server
+	routes      map[route][]Response
+	requests    []Request
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	used        int
+	stream      *stream
+	mu          sync.Mutex
+}
+
+func NewServer() *Server {
+	s := &Server{
+		routes: make(map[route][]Response),
+		stream: newStream(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.stream.handle)
+	mux.HandleFunc("/", s.handleREST)
+
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	s.StreamURL = "ws" + strings.TrimPrefix(s.server.URL, "http") + "/ws"
+	return s
+}
+
+func (s *Server) Close() {
+	s.stream.closeAll()
+	s.server.Close()
+}
+
+// Handle programs the responses for path (e.g. "/quote") and symbol, or for
+// every symbol when symbol is empty. Each request takes the next response;
+// the last one repeats, so Handle(p, s, fail, fail, ok) fails twice and then
+// keeps succeeding.
+func (s *Server) Handle(path, symbol string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[route{path: path, symbol: symbol}] = responses
+}
+
+// SetJSON serves v as the response for path and symbol.
+func (s *Server) SetJSON(path, symbol string, v any) {
+	s.Handle(path, symbol, Response{Body: v})
+}
+
+func (s *Server) SetQuote(symbol string, quote models.StockQuote) {
+	s.SetJSON("/quote", symbol, quote)
+}
+
+// SetError makes path and symbol fail with status.
+func (s *Server) SetError(path, symbol string, status int) {
+	s.Handle(path, symbol, Response{Status: status, Body: map[string]string{"error": http.StatusText(status)}})
+}
+
+// SetRateLimit allows limit requests per window, reporting the budget in
+// X-Ratelimit-* headers like Finnhub and answering 429 with Retry-After once
+// it is spent. A zero limit removes the limit.
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+	s.window = window
+	s.windowStart = time.Now()
+	s.used = 0
+}
+
+// Requests returns every REST request received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestCount counts the REST requests received for path.
+func (s *Server) RequestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, req := range s.requests {
+		if req.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) handleREST(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token := query.Get("token")
+	if token == "" {
+		token = r.Header.Get("X-Finnhub-Token")
+	}
+	query.Del("token")
+
+	symbol := query.Get("symbol")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Path: r.URL.Path, Symbol: symbol, Query: query, Token: token})
+	latency := s.Latency
+	limited, retryAfter := s.takeToken(w.Header())
+	response, found := s.next(r.URL.Path, symbol)
+	authorized := s.Token == "" || token == s.Token
+	s.mu.Unlock()
+
+	if !sleep(r, latency+response.Latency) {
+		return
+	}
+
+	switch {
+	case !authorized:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid API key."})
+	case limited:
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "API limit reached. Please try again later."})
+	case !found:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("no fake response for %s %q", r.URL.Path, symbol)})
+	default:
+		for name, values := range response.Header {
+			w.Header()[name] = values
+		}
+		status := response.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, response.Body)
+	}
+}
+
+// next must be called with s.mu held.
+func (s *Server) next(path, symbol string) (Response, bool) {
+	key := route{path: path, symbol: symbol}
+	responses, ok := s.routes[key]
+	if !ok {
+		key.symbol = ""
+		responses, ok = s.routes[key]
+	}
+	if !ok || len(responses) == 0 {
+		return Response{}, false
+	}
+
+	if len(responses) > 1 {
+		s.routes[key] = responses[1:]
+	}
+	return responses[0], true
+}
+
+// takeToken must be called with s.mu held. It reports whether the request is
+// over the limit and, if so, the seconds until the window resets.
+func (s *Server) takeToken(header http.Header) (bool, int) {
+	if s.limit <= 0 {
+		return false, 0
+	}
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.window {
+		s.windowStart = now
+		s.used = 0
+	}
+	reset := s.windowStart.Add(s.window)
+
+	s.used++
+	remaining := max(s.limit-s.used, 0)
+
+	header.Set("X-Ratelimit-Limit", strconv.Itoa(s.limit))
+	header.Set("X-Ratelimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-Ratelimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+	if s.used <= s.limit {
+		return false, 0
+	}
+	return true, int(math.Ceil(time.Until(reset).Seconds()))
+}
+
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	var data []byte
+	switch b := body.(type) {
+	case []byte:
+		data = b
+	case string:
+		data = []byte(b)
+	default:
+		var err error
+		if data, err = json.Marshal(b); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// upgrader accepts every origin; it is the only upgrader tests need.
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
@@ -0,0 +1,179 @@
+package finnhubtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/finnhub"
+	"github.com/rinz5/co-finance/backend/internal/finnhub/finnhubtest"
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+func newClient(fake *finnhubtest.Server) *finnhub.Client {
+	client := finnhub.NewClient("fake-key")
+	client.BaseURL = fake.URL
+	client.Retry = finnhub.RetryPolicy{MaxAttempts: 1}
+	return client
+}
+
+func TestServerPerSymbolResponses(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	fake.SetQuote("AAPL", models.StockQuote{CurrentPrice: 150, Timestamp: 1700000000})
+	fake.SetQuote("", models.StockQuote{CurrentPrice: 1, Timestamp: 1700000000})
+
+	client := newClient(fake)
+
+	if quote, err := client.GetQuote("AAPL"); err != nil || quote.CurrentPrice != 150 {
+		t.Errorf("Expected AAPL at 150, got %+v, %v", quote, err)
+	}
+	if quote, err := client.GetQuote("MSFT"); err != nil || quote.CurrentPrice != 1 {
+		t.Errorf("Expected fallback quote at 1, got %+v, %v", quote, err)
+	}
+	if _, err := client.GetEarnings("AAPL"); !errors.Is(err, finnhub.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unprogrammed endpoint, got %v", err)
+	}
+
+	if n := fake.RequestCount("/quote"); n != 2 {
+		t.Errorf("Expected 2 quote requests, got %d", n)
+	}
+	if req := fake.Requests()[0]; req.Token != "fake-key" || req.Query.Get("token") != "" {
+		t.Errorf("Expected token to be captured and stripped, got %+v", req)
+	}
+}
+
+func TestServerResponseSequence(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	fake.Handle("/quote", "AAPL",
+		finnhubtest.Response{Status: http.StatusServiceUnavailable},
+		finnhubtest.Response{Body: models.StockQuote{CurrentPrice: 150, Timestamp: 1700000000}},
+	)
+
+	client := newClient(fake)
+	client.Retry = finnhub.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	if quote, err := client.GetQuote("AAPL"); err != nil || quote.CurrentPrice != 150 {
+		t.Errorf("Expected retry to succeed, got %+v, %v", quote, err)
+	}
+	if _, err := client.GetQuote("AAPL"); err != nil {
+		t.Errorf("Expected the last response to repeat, got %v", err)
+	}
+	if n := fake.RequestCount("/quote"); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	fake.SetQuote("", models.StockQuote{CurrentPrice: 150, Timestamp: 1700000000})
+	fake.SetError("/stock/metric", "", http.StatusBadGateway)
+	fake.Token = "real-key"
+
+	client := newClient(fake)
+
+	if _, err := client.GetQuote("AAPL"); !errors.Is(err, finnhub.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized for the wrong key, got %v", err)
+	}
+
+	client.ApiKey = "real-key"
+	if _, err := client.GetBasicFinancials("AAPL"); !errors.Is(err, finnhub.ErrUpstreamUnavailable) {
+		t.Errorf("Expected ErrUpstreamUnavailable, got %v", err)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	fake.SetQuote("", models.StockQuote{CurrentPrice: 150, Timestamp: 1700000000})
+	fake.SetRateLimit(2, time.Minute)
+
+	client := newClient(fake)
+	client.Limiter = nil
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetQuote("AAPL"); err != nil {
+			t.Fatalf("Expected request %d within the limit, got %v", i, err)
+		}
+	}
+
+	_, err := client.GetQuote("AAPL")
+	var apiErr *finnhub.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, finnhub.ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+	if apiErr.RetryAfter <= 0 || apiErr.RetryAfter > time.Minute {
+		t.Errorf("Expected Retry-After within the window, got %v", apiErr.RetryAfter)
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	fake.SetQuote("", models.StockQuote{CurrentPrice: 150, Timestamp: 1700000000})
+	fake.Latency = time.Second
+
+	client := newClient(fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.GetQuoteContext(ctx, "AAPL"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestServerScriptedStream(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	stream := finnhub.NewStreamClient("fake-key", []string{"AAPL"})
+	stream.BaseURL = fake.StreamURL
+	defer stream.Stop()
+
+	frames := make(chan []byte, 4)
+	go stream.Start(frames)
+
+	if !fake.WaitForSubscription("AAPL", time.Second) {
+		t.Fatal("Expected AAPL subscription")
+	}
+
+	done := fake.Play(
+		finnhubtest.Step{Frame: []byte(`{"type":"ping"}`)},
+		finnhubtest.Step{Delay: 10 * time.Millisecond, Trades: []models.Trade{
+			{Price: 150, Symbol: "AAPL", Timestamp: 1700000000000, Volume: 10},
+			{Price: 400, Symbol: "MSFT", Timestamp: 1700000000000, Volume: 5},
+		}},
+	)
+	<-done
+
+	var got []*finnhub.Frame
+	for len(got) < 2 {
+		select {
+		case raw := <-frames:
+			frame, err := finnhub.DecodeFrame(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, frame)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 2 frames, got %d", len(got))
+		}
+	}
+
+	if got[0].Type != finnhub.FramePing {
+		t.Errorf("Expected ping first, got %s", got[0].Type)
+	}
+	if len(got[1].Trades) != 1 || got[1].Trades[0].Symbol != "AAPL" {
+		t.Errorf("Expected only the subscribed AAPL trade, got %+v", got[1].Trades)
+	}
+}
@@ -0,0 +1,251 @@
+package finnhubtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+// Step is one scripted stream event: after Delay, Trades are sent to every
+// connection subscribed to their symbols, or Frame is sent as is to all.
+type Step struct {
+	Delay  time.Duration
+	Trades []models.Trade
+	Frame  []byte
+}
+
+type streamConn struct {
+	conn       *websocket.Conn
+	subscribed map[string]bool
+	mu         sync.Mutex
+	writeMu    sync.Mutex
+}
+
+func (c *streamConn) write(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+type stream struct {
+	conns       map[*streamConn]bool
+	connections int
+	messages    []string
+	changed     chan struct{}
+	mu          sync.Mutex
+}
+
+func newStream() *stream {
+	return &stream{
+		conns:   make(map[*streamConn]bool),
+		changed: make(chan struct{}),
+	}
+}
+
+func (st *stream) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &streamConn{conn: conn, subscribed: make(map[string]bool)}
+
+	st.mu.Lock()
+	st.conns[c] = true
+	st.connections++
+	st.notify()
+	st.mu.Unlock()
+
+	defer func() {
+		st.mu.Lock()
+		delete(st.conns, c)
+		st.notify()
+		st.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg struct {
+			Type   string `json:"type"`
+			Symbol string `json:"symbol"`
+		}
+		json.Unmarshal(data, &msg)
+
+		st.mu.Lock()
+		st.messages = append(st.messages, string(data))
+		c.mu.Lock()
+		switch msg.Type {
+		case "subscribe":
+			c.subscribed[msg.Symbol] = true
+		case "unsubscribe":
+			delete(c.subscribed, msg.Symbol)
+		}
+		c.mu.Unlock()
+		st.notify()
+		st.mu.Unlock()
+	}
+}
+
+// notify must be called with st.mu held.
+func (st *stream) notify() {
+	close(st.changed)
+	st.changed = make(chan struct{})
+}
+
+func (st *stream) snapshot() []*streamConn {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	conns := make([]*streamConn, 0, len(st.conns))
+	for c := range st.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func (st *stream) closeAll() {
+	for _, c := range st.snapshot() {
+		c.conn.Close()
+	}
+}
+
+// waitFor blocks until cond, checked with st.mu held, is true or timeout
+// passes.
+func (st *stream) waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		st.mu.Lock()
+		ok := cond()
+		changed := st.changed
+		st.mu.Unlock()
+
+		if ok {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+// SendTrades sends a trade frame to every stream connection subscribed to the
+// trades' symbols, each connection only seeing its own symbols like on
+// Finnhub.
+func (s *Server) SendTrades(trades ...models.Trade) {
+	for _, c := range s.stream.snapshot() {
+		c.mu.Lock()
+		var visible []models.Trade
+		for _, trade := range trades {
+			if c.subscribed[trade.Symbol] {
+				visible = append(visible, trade)
+			}
+		}
+		c.mu.Unlock()
+
+		if len(visible) == 0 {
+			continue
+		}
+
+		data, _ := json.Marshal(models.TradeMessage{Type: "trade", Data: visible})
+		c.write(data)
+	}
+}
+
+// SendFrame sends a raw frame to every stream connection.
+func (s *Server) SendFrame(frame []byte) {
+	for _, c := range s.stream.snapshot() {
+		c.write(frame)
+	}
+}
+
+// Play runs script in the background. The returned channel is closed once
+// every step has been sent.
+func (s *Server) Play(script ...Step) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for _, step := range script {
+			time.Sleep(step.Delay)
+			if step.Frame != nil {
+				s.SendFrame(step.Frame)
+			}
+			if len(step.Trades) > 0 {
+				s.SendTrades(step.Trades...)
+			}
+		}
+	}()
+
+	return done
+}
+
+// DropStreams closes every open stream connection, e.g. to test reconnects.
+func (s *Server) DropStreams() {
+	s.stream.closeAll()
+}
+
+// StreamConnections counts stream connections accepted so far, including
+// closed ones.
+func (s *Server) StreamConnections() int {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	return s.stream.connections
+}
+
+// StreamMessages returns every message clients sent on the stream.
+func (s *Server) StreamMessages() []string {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	return append([]string(nil), s.stream.messages...)
+}
+
+// WaitForConnections waits until n stream connections have been accepted.
+func (s *Server) WaitForConnections(n int, timeout time.Duration) bool {
+	return s.stream.waitFor(timeout, func() bool {
+		return s.stream.connections >= n
+	})
+}
+
+// WaitForSubscription waits until an open stream connection is subscribed
+// to symbol.
+func (s *Server) WaitForSubscription(symbol string, timeout time.Duration) bool {
+	return s.stream.waitFor(timeout, func() bool {
+		return s.subscribedLocked(symbol)
+	})
+}
+
+// WaitForUnsubscription waits until no open stream connection is subscribed
+// to symbol.
+func (s *Server) WaitForUnsubscription(symbol string, timeout time.Duration) bool {
+	return s.stream.waitFor(timeout, func() bool {
+		return !s.subscribedLocked(symbol)
+	})
+}
+
+// subscribedLocked must be called with s.stream.mu held.
+func (s *Server) subscribedLocked(symbol string) bool {
+	for c := range s.stream.conns {
+		c.mu.Lock()
+		ok := c.subscribed[symbol]
+		c.mu.Unlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
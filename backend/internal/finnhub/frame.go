@@ -0,0 +1,64 @@
+package finnhub
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+type FrameType string
+
+const (
+	FrameTrade FrameType = "trade"
+	FramePing  FrameType = "ping"
+	FrameError FrameType = "error"
+)
+
+// Frame is a decoded message from the Finnhub websocket. Trades is set for
+// trade frames and Message for error frames.
+type Frame struct {
+	Type    FrameType
+	Trades  []models.Trade
+	Message string
+}
+
+func DecodeFrame(data []byte) (*Frame, error) {
+	var wire struct {
+		Type FrameType      `json:"type"`
+		Data []models.Trade `json:"data"`
+		Msg  string         `json:"msg"`
+	}
+
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+
+	switch wire.Type {
+	case FrameTrade:
+		return &Frame{Type: FrameTrade, Trades: wire.Data}, nil
+	case FramePing:
+		return &Frame{Type: FramePing}, nil
+	case FrameError:
+		return &Frame{Type: FrameError, Message: wire.Msg}, nil
+	default:
+		return nil, fmt.Errorf("decode frame: unknown type %q", wire.Type)
+	}
+}
+
+// ValidTrades drops trades Finnhub occasionally sends without a symbol,
+// price or timestamp.
+func ValidTrades(trades []models.Trade) []models.Trade {
+	valid := make([]models.Trade, 0, len(trades))
+	for _, trade := range trades {
+		if trade.Symbol == "" || trade.Price <= 0 || trade.Timestamp <= 0 || trade.Volume < 0 {
+			continue
+		}
+		valid = append(valid, trade)
+	}
+	return valid
+}
+
+func EncodeTrades(trades []models.Trade) ([]byte, error) {
+	return json.Marshal(models.TradeMessage{Type: string(FrameTrade), Data: trades})
+}
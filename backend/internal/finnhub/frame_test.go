@@ -0,0 +1,93 @@
+package finnhub
+
+import (
+	"testing"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+func TestDecodeTradeFrame(t *testing.T) {
+	frame, err := DecodeFrame([]byte(`{
+		"type": "trade",
+		"data": [
+			{"p": 7296.89, "s": "BINANCE:BTCUSDT", "t": 1575526691134, "v": 0.011467, "c": ["1", "12"]}
+		]
+	}`))
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if frame.Type != FrameTrade {
+		t.Errorf("Expected trade frame, got %s", frame.Type)
+	}
+
+	if len(frame.Trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(frame.Trades))
+	}
+
+	trade := frame.Trades[0]
+	if trade.Price != 7296.89 {
+		t.Errorf("Expected price 7296.89, got %f", trade.Price)
+	}
+	if trade.Symbol != "BINANCE:BTCUSDT" {
+		t.Errorf("Expected symbol BINANCE:BTCUSDT, got %s", trade.Symbol)
+	}
+	if trade.Time().UnixMilli() != 1575526691134 {
+		t.Errorf("Expected timestamp 1575526691134, got %d", trade.Time().UnixMilli())
+	}
+	if len(trade.Conditions) != 2 {
+		t.Errorf("Expected 2 conditions, got %v", trade.Conditions)
+	}
+}
+
+func TestDecodePingAndErrorFrames(t *testing.T) {
+	ping, err := DecodeFrame([]byte(`{"type":"ping"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ping.Type != FramePing {
+		t.Errorf("Expected ping frame, got %s", ping.Type)
+	}
+
+	errFrame, err := DecodeFrame([]byte(`{"type":"error","msg":"Invalid symbol"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if errFrame.Type != FrameError || errFrame.Message != "Invalid symbol" {
+		t.Errorf("Expected error frame with message, got %+v", errFrame)
+	}
+}
+
+func TestDecodeInvalidFrames(t *testing.T) {
+	if _, err := DecodeFrame([]byte(`not json`)); err == nil {
+		t.Error("Expected error for malformed frame")
+	}
+
+	if _, err := DecodeFrame([]byte(`{"type":"news"}`)); err == nil {
+		t.Error("Expected error for unknown frame type")
+	}
+}
+
+func TestValidTradesAndEncode(t *testing.T) {
+	trades := ValidTrades([]models.Trade{
+		{Price: 100.5, Symbol: "AAPL", Timestamp: 1575526691134, Volume: 10},
+		{Price: 100.5, Symbol: "", Timestamp: 1575526691134, Volume: 10},
+		{Price: 0, Symbol: "AAPL", Timestamp: 1575526691134, Volume: 10},
+		{Price: 100.5, Symbol: "AAPL", Timestamp: 0, Volume: 10},
+	})
+
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 valid trade, got %d", len(trades))
+	}
+
+	encoded, err := EncodeTrades(trades)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := `{"type":"trade","data":[{"p":100.5,"s":"AAPL","t":1575526691134,"v":10}]}`
+	if string(encoded) != want {
+		t.Errorf("Expected %s, got %s", want, encoded)
+	}
+}
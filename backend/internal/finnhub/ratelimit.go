@@ -0,0 +1,242 @@
+package finnhub
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Priority int
+
+const (
+	// PriorityInteractive is for requests a user is waiting on.
+	PriorityInteractive Priority = iota
+	// PriorityBackground is for refresh jobs; it only gets tokens while no
+	// interactive request is queued.
+	PriorityBackground
+)
+
+type priorityKey struct{}
+
+// WithPriority tags requests made with ctx with the given limiter lane.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+type RateLimits struct {
+	PerSecond int
+	PerMinute int
+}
+
+// DefaultRateLimits matches Finnhub's free tier.
+func DefaultRateLimits() RateLimits {
+	return RateLimits{PerSecond: 30, PerMinute: 60}
+}
+
+type RateLimiterStats struct {
+	Waits     uint64 `json:"waits"`
+	Throttled uint64 `json:"throttled"`
+	Queued    int    `json:"queued"`
+}
+
+type bucket struct {
+	capacity float64
+	tokens   float64
+	rate     float64
+	last     time.Time
+}
+
+func newBucket(limit int, per time.Duration) *bucket {
+	return &bucket{
+		capacity: float64(limit),
+		tokens:   float64(limit),
+		rate:     float64(limit) / per.Seconds(),
+		last:     time.Now(),
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+func (b *bucket) untilToken() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// RateLimiter is a token bucket limiter shared by every Client method. It
+// enforces both a per-second and a per-minute budget, serves interactive
+// requests ahead of background ones and backs off when Finnhub's rate-limit
+// headers report the quota as spent.
+type RateLimiter struct {
+	buckets      []*bucket
+	queued       [2]int
+	blockedUntil time.Time
+	changed      chan struct{}
+	waits        uint64
+	throttled    uint64
+	mu           sync.Mutex
+}
+
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	l := &RateLimiter{changed: make(chan struct{})}
+
+	if limits.PerSecond > 0 {
+		l.buckets = append(l.buckets, newBucket(limits.PerSecond, time.Second))
+	}
+	if limits.PerMinute > 0 {
+		l.buckets = append(l.buckets, newBucket(limits.PerMinute, time.Minute))
+	}
+
+	return l
+}
+
+// Wait blocks until a request may be sent or ctx is done. The lane is taken
+// from ctx, see WithPriority.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	priority := priorityFrom(ctx)
+
+	l.mu.Lock()
+	l.queued[priority]++
+	defer func() {
+		l.queued[priority]--
+		l.notify()
+		l.mu.Unlock()
+	}()
+
+	waited := false
+	for {
+		delay := l.reserve(time.Now(), priority)
+		if delay == 0 {
+			if waited {
+				l.waits++
+			}
+			return nil
+		}
+		waited = true
+
+		changed := l.changed
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.mu.Lock()
+			return ctx.Err()
+		case <-timer.C:
+		case <-changed:
+			timer.Stop()
+		}
+
+		l.mu.Lock()
+	}
+}
+
+// Observe adapts the limiter to Finnhub's X-Ratelimit-* and Retry-After
+// response headers.
+func (l *RateLimiter) Observe(status int, header http.Header) {
+	now := time.Now()
+
+	var until time.Time
+	if remaining, err := strconv.Atoi(header.Get("X-Ratelimit-Remaining")); err == nil && remaining <= 0 {
+		if reset, err := strconv.ParseInt(header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			until = time.Unix(reset, 0)
+		}
+	}
+
+	if status == http.StatusTooManyRequests {
+		if retryAfter := parseRetryAfter(header.Get("Retry-After")); retryAfter > 0 {
+			until = now.Add(retryAfter)
+		} else if until.IsZero() {
+			until = now.Add(time.Second)
+		}
+	}
+
+	if !until.After(now) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until.After(l.blockedUntil) {
+		l.blockedUntil = until
+		l.throttled++
+		l.notify()
+	}
+}
+
+func (l *RateLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return RateLimiterStats{
+		Waits:     l.waits,
+		Throttled: l.throttled,
+		Queued:    l.queued[PriorityInteractive] + l.queued[PriorityBackground],
+	}
+}
+
+// reserve takes a token from every bucket and returns 0, or returns how long
+// to wait before trying again. It must be called with l.mu held.
+func (l *RateLimiter) reserve(now time.Time, priority Priority) time.Duration {
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now)
+	}
+
+	var delay time.Duration
+	for _, b := range l.buckets {
+		b.refill(now)
+		delay = max(delay, b.untilToken())
+	}
+
+	if priority == PriorityBackground && l.queued[PriorityInteractive] > 0 {
+		// Woken up again through notify once the interactive lane drains.
+		return max(delay, time.Second)
+	}
+
+	if delay > 0 {
+		return delay
+	}
+
+	for _, b := range l.buckets {
+		b.tokens--
+	}
+	return 0
+}
+
+// notify wakes every waiter to re-check the limiter. It must be called with
+// l.mu held.
+func (l *RateLimiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+
+	return 0
+}
@@ -0,0 +1,115 @@
+package finnhub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterEnforcesBudget(t *testing.T) {
+	limiter := NewRateLimiter(RateLimits{PerSecond: 2})
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 3 {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected third request to wait for a token, took %s", elapsed)
+	}
+
+	if stats := limiter.Stats(); stats.Waits != 1 {
+		t.Errorf("Expected 1 wait, got %d", stats.Waits)
+	}
+}
+
+func TestRateLimiterHonorsContext(t *testing.T) {
+	limiter := NewRateLimiter(RateLimits{PerMinute: 1})
+	limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	if stats := limiter.Stats(); stats.Queued != 0 {
+		t.Errorf("Expected cancelled waiter to leave the queue, got %d queued", stats.Queued)
+	}
+}
+
+func TestRateLimiterPrefersInteractive(t *testing.T) {
+	limiter := NewRateLimiter(RateLimits{PerSecond: 20})
+	for range 20 {
+		limiter.Wait(context.Background())
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+
+	wait := func(name string, ctx context.Context) {
+		defer wg.Done()
+		if err := limiter.Wait(ctx); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+
+	wg.Add(2)
+	go wait("background", WithPriority(context.Background(), PriorityBackground))
+	time.Sleep(10 * time.Millisecond)
+	go wait("interactive", context.Background())
+	wg.Wait()
+
+	if len(order) != 2 || order[0] != "interactive" {
+		t.Errorf("Expected interactive request to be served first, got %v", order)
+	}
+}
+
+func TestRateLimiterObservesHeaders(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimits())
+
+	header := http.Header{}
+	header.Set("X-Ratelimit-Remaining", "0")
+	header.Set("X-Ratelimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	limiter.Observe(http.StatusOK, header)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected limiter to block until the quota resets, got %v", err)
+	}
+
+	if stats := limiter.Stats(); stats.Throttled != 1 {
+		t.Errorf("Expected 1 throttle, got %d", stats.Throttled)
+	}
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimits())
+
+	header := http.Header{}
+	header.Set("Retry-After", "1")
+	limiter.Observe(http.StatusTooManyRequests, header)
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("Expected limiter to honor Retry-After, took %s", elapsed)
+	}
+}
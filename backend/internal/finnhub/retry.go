@@ -0,0 +1,83 @@
+package finnhub
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+	}
+}
+
+type RetryStats struct {
+	Requests uint64 `json:"requests"`
+	Attempts uint64 `json:"attempts"`
+	Retries  uint64 `json:"retries"`
+	Failures uint64 `json:"failures"`
+}
+
+// retryable reports whether a failed attempt may succeed when repeated: all
+// Finnhub REST calls are idempotent GETs, so network errors, 5xx and 429 are
+// retried while client errors, decode failures and cancellations are not.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == 429
+	}
+
+	return true
+}
+
+// delay is the wait before the given retry (0 for the first one). A
+// Retry-After sent by Finnhub takes precedence over the backoff; when it is
+// longer than MaxDelay, delay reports false and the request is not retried,
+// so the caller sees the 429 instead of holding on to the request.
+func (p RetryPolicy) delay(retry int, err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, apiErr.RetryAfter <= p.MaxDelay
+	}
+	return exponentialBackoff(p.BaseDelay, p.MaxDelay, retry), true
+}
+
+// exponentialBackoff doubles base per attempt up to max and applies jitter in
+// the upper half of the interval.
+func exponentialBackoff(base, max time.Duration, attempt int) time.Duration {
+	delay := max
+	if attempt < 32 {
+		if d := base << attempt; d > 0 && d < max {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
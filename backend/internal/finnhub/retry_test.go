@@ -0,0 +1,229 @@
+package finnhub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer fails the first failures requests with status, then serves
+// a valid quote.
+func newFlakyServer(failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"c": 261.74, "t": 1582641000}`))
+	}))
+	return server, &requests
+}
+
+func newRetryTestClient(url string) *Client {
+	client := NewClient("fake-key")
+	client.BaseURL = url
+	client.Limiter = nil
+	client.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	return client
+}
+
+func TestRetryRecoversFromServerErrors(t *testing.T) {
+	mockServer, requests := newFlakyServer(2, http.StatusBadGateway, nil)
+	defer mockServer.Close()
+
+	client := newRetryTestClient(mockServer.URL)
+
+	quote, err := client.GetQuote("AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quote.CurrentPrice != 261.74 {
+		t.Errorf("Expected price 261.74, got %f", quote.CurrentPrice)
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests.Load())
+	}
+
+	stats := client.RetryStats()
+	if stats.Requests != 1 || stats.Attempts != 3 || stats.Retries != 2 || stats.Failures != 0 {
+		t.Errorf("Unexpected retry stats %+v", stats)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	mockServer, requests := newFlakyServer(5, http.StatusServiceUnavailable, nil)
+	defer mockServer.Close()
+
+	client := newRetryTestClient(mockServer.URL)
+
+	_, err := client.GetQuote("AAPL")
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("Expected upstream unavailable, got %v", err)
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests.Load())
+	}
+
+	if stats := client.RetryStats(); stats.Failures != 1 {
+		t.Errorf("Expected 1 failure, got %d", stats.Failures)
+	}
+}
+
+func TestRetrySkipsClientErrors(t *testing.T) {
+	mockServer, requests := newFlakyServer(5, http.StatusUnauthorized, nil)
+	defer mockServer.Close()
+
+	client := newRetryTestClient(mockServer.URL)
+
+	if _, err := client.GetQuote("AAPL"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected unauthorized, got %v", err)
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("Expected no retries for a 401, got %d requests", requests.Load())
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "1")
+	mockServer, requests := newFlakyServer(1, http.StatusTooManyRequests, header)
+	defer mockServer.Close()
+
+	client := newRetryTestClient(mockServer.URL)
+	client.Retry.MaxDelay = 2 * time.Second
+
+	start := time.Now()
+	if _, err := client.GetQuote("AAPL"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("Expected retry to wait for Retry-After, took %s", elapsed)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestRetryGivesUpOnLongRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "3600")
+	mockServer, requests := newFlakyServer(1, http.StatusTooManyRequests, header)
+	defer mockServer.Close()
+
+	client := newRetryTestClient(mockServer.URL)
+
+	start := time.Now()
+	_, err := client.GetQuote("AAPL")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected the 429 to be returned, got %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != time.Hour {
+		t.Errorf("Expected Retry-After to be kept for the caller, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected no wait, took %s", elapsed)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected 1 request, got %d", requests.Load())
+	}
+}
+
+func TestRetryStopsWhenContextEnds(t *testing.T) {
+	mockServer, _ := newFlakyServer(5, http.StatusInternalServerError, nil)
+	defer mockServer.Close()
+
+	client := newRetryTestClient(mockServer.URL)
+	client.Retry = RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.GetQuoteContext(ctx, "AAPL"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded while backing off, got %v", err)
+	}
+}
+
+func TestRetryRecoversFromNetworkErrors(t *testing.T) {
+	mockServer, _ := newFlakyServer(0, http.StatusOK, nil)
+	defer mockServer.Close()
+
+	var calls atomic.Int32
+	client := newRetryTestClient(mockServer.URL)
+	client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+
+	if _, err := client.GetQuote("AAPL"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls.Load())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestRetryLogsDoNotLeakToken(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	client := newRetryTestClient("http://" + addr)
+	client.ApiKey = "secret-key"
+
+	_, err = client.GetQuote("AAPL")
+	if err == nil {
+		t.Fatal("Expected a connection error")
+	}
+	if client.RetryStats().Retries == 0 {
+		t.Fatal("Expected the connection error to be retried")
+	}
+
+	if strings.Contains(logs.String(), "secret-key") {
+		t.Errorf("Expected the API key to be redacted from logs, got %s", logs.String())
+	}
+	if strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("Expected the API key to be redacted from the error, got %v", err)
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	got := redactToken("https://finnhub.io/api/v1/quote?symbol=AAPL&token=secret")
+	if got != "https://finnhub.io/api/v1/quote?symbol=AAPL&token=REDACTED" {
+		t.Errorf("Expected token to be redacted, got %s", got)
+	}
+}
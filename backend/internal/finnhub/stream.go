@@ -1,79 +1,270 @@
 package finnhub
 
 import (
+	"errors"
 	"log"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const (
+	DefaultStreamURL  = "wss://ws.finnhub.io?token="
+	DefaultMinBackoff = 1 * time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+var errStreamStopped = errors.New("stream client stopped")
+
+type ConnState int
+
+const (
+	StateDisconnected ConnState = iota
+	StateConnecting
+	StateConnected
+	StateBackingOff
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateBackingOff:
+		return "backing_off"
+	default:
+		return "unknown"
+	}
+}
+
 type StreamClient struct {
 	Token         string
 	Symbols       []string
 	BaseURL       string
-	subscribeChan chan string
-	conn          *websocket.Conn
-	mu            sync.Mutex
+	MinBackoff    time.Duration
+	MaxBackoff    time.Duration
+	OnStateChange func(ConnState)
+	// OnFrame, if set, sees every raw frame as it is read, e.g. to record
+	// the session.
+	OnFrame    func(received time.Time, frame []byte)
+	subscribed map[string]bool
+	state      ConnState
+	conn       *websocket.Conn
+	done       chan struct{}
+	stopOnce   sync.Once
+	mu         sync.Mutex
 }
 
 func NewStreamClient(token string, symbols []string) *StreamClient {
 	return &StreamClient{
-		Token:         token,
-		Symbols:       symbols,
-		BaseURL:       "wss://ws.finnhub.io?token=",
-		subscribeChan: make(chan string),
+		Token:      token,
+		Symbols:    symbols,
+		BaseURL:    DefaultStreamURL,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		subscribed: make(map[string]bool),
+		done:       make(chan struct{}),
 	}
 }
 
+// Subscribe records the symbol so it is replayed after every reconnect and
+// forwards it upstream immediately when a connection is open.
 func (s *StreamClient) Subscribe(symbol string) {
-	s.subscribeChan <- symbol
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscribed[symbol] {
+		return
+	}
+	s.subscribed[symbol] = true
+
+	if s.conn != nil {
+		s.sendSubscribe(s.conn, symbol)
+	}
 }
 
+// Unsubscribe drops the symbol from the replay set and tells Finnhub to stop
+// sending its trades.
+func (s *StreamClient) Unsubscribe(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.subscribed[symbol] {
+		return
+	}
+	delete(s.subscribed, symbol)
+
+	if s.conn != nil {
+		s.sendUnsubscribe(s.conn, symbol)
+	}
+}
+
+func (s *StreamClient) State() ConnState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Start runs the connection loop until Stop is called, redialing with
+// exponential backoff whenever the dial or the read loop fails.
 func (s *StreamClient) Start(outputChan chan<- []byte) {
+	s.mu.Lock()
+	for _, sym := range s.Symbols {
+		s.subscribed[sym] = true
+	}
+	s.mu.Unlock()
+
+	defer s.setState(StateDisconnected)
+
+	attempt := 0
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		s.setState(StateConnecting)
+
+		conn, err := s.connect()
+		if err != nil {
+			log.Printf("Finnhub connection error: %v", err)
+		} else {
+			attempt = 0
+			s.setState(StateConnected)
+			s.readLoop(conn, outputChan)
+		}
+
+		s.setState(StateBackingOff)
+
+		delay := s.backoff(attempt)
+		attempt++
+		log.Printf("Reconnecting to Finnhub in %s", delay)
+
+		select {
+		case <-time.After(delay):
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Stop terminates the connection loop and closes the active connection.
+func (s *StreamClient) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+	})
+}
+
+func (s *StreamClient) connect() (*websocket.Conn, error) {
 	url := s.BaseURL
-	if s.BaseURL == "wss://ws.finnhub.io?token=" {
+	if s.BaseURL == DefaultStreamURL {
 		url = s.BaseURL + s.Token
 	}
 
-	var err error
-	s.conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
-		log.Printf("Finnhub connection error: %v", err)
-		return
+		return nil, err
 	}
-	defer s.conn.Close()
 
-	for _, sym := range s.Symbols {
-		s.sendSubscribe(sym)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		conn.Close()
+		return nil, errStreamStopped
+	default:
 	}
 
-	go s.readLoop(outputChan)
+	symbols := make([]string, 0, len(s.subscribed))
+	for sym := range s.subscribed {
+		symbols = append(symbols, sym)
+	}
+	slices.Sort(symbols)
 
-	for symbol := range s.subscribeChan {
-		s.sendSubscribe(symbol)
+	for _, sym := range symbols {
+		s.sendSubscribe(conn, sym)
 	}
-}
 
-func (s *StreamClient) sendSubscribe(symbol string) {
-	msg := map[string]any{"type": "subscribe", "symbol": symbol}
+	s.conn = conn
+	return conn, nil
+}
 
+func (s *StreamClient) setState(state ConnState) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	changed := s.state != state
+	s.state = state
+	s.mu.Unlock()
+
+	if changed && s.OnStateChange != nil {
+		s.OnStateChange(state)
+	}
+}
+
+func (s *StreamClient) backoff(attempt int) time.Duration {
+	return exponentialBackoff(s.MinBackoff, s.MaxBackoff, attempt)
+}
+
+// sendSubscribe must be called with s.mu held.
+func (s *StreamClient) sendSubscribe(conn *websocket.Conn, symbol string) {
+	msg := map[string]any{"type": "subscribe", "symbol": symbol}
 
-	if err := s.conn.WriteJSON(msg); err != nil {
+	if err := conn.WriteJSON(msg); err != nil {
 		log.Printf("Subscribe error: %v", err)
 	} else {
 		log.Printf("Subscribed to %s", symbol)
 	}
 }
 
-func (s *StreamClient) readLoop(outputChan chan<- []byte) {
+// sendUnsubscribe must be called with s.mu held.
+func (s *StreamClient) sendUnsubscribe(conn *websocket.Conn, symbol string) {
+	msg := map[string]any{"type": "unsubscribe", "symbol": symbol}
+
+	if err := conn.WriteJSON(msg); err != nil {
+		log.Printf("Unsubscribe error: %v", err)
+	} else {
+		log.Printf("Unsubscribed from %s", symbol)
+	}
+}
+
+func (s *StreamClient) readLoop(conn *websocket.Conn, outputChan chan<- []byte) {
+	defer func() {
+		s.mu.Lock()
+		if s.conn == conn {
+			s.conn = nil
+		}
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
 	for {
-		_, message, err := s.conn.ReadMessage()
+		_, message, err := conn.ReadMessage()
 		if err != nil {
 			log.Printf("Read error: %v", err)
 			return
 		}
-		outputChan <- message
+
+		if s.OnFrame != nil {
+			s.OnFrame(time.Now(), message)
+		}
+
+		select {
+		case outputChan <- message:
+		case <-s.done:
+			return
+		}
 	}
 }
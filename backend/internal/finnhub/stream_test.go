@@ -1,77 +1,200 @@
 package finnhub
 
 import (
-	"net/http"
-	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/rinz5/co-finance/backend/internal/finnhub/finnhubtest"
+	"github.com/rinz5/co-finance/backend/internal/models"
 )
 
-var upgrader = websocket.Upgrader{}
+func newTestStreamClient(fake *finnhubtest.Server, symbols []string) *StreamClient {
+	client := NewStreamClient("fake-token", symbols)
+	client.BaseURL = fake.StreamURL
+	client.MinBackoff = 10 * time.Millisecond
+	client.MaxBackoff = 20 * time.Millisecond
+	return client
+}
 
-func TestStreamClient(t *testing.T) {
-	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		c, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			return
+func countMessages(messages []string, substrings ...string) int {
+	count := 0
+	for _, msg := range messages {
+		matches := true
+		for _, s := range substrings {
+			matches = matches && strings.Contains(msg, s)
 		}
-		defer c.Close()
-
-		_, msg, _ := c.ReadMessage()
-		if !strings.Contains(string(msg), "subscribe") {
-			t.Errorf("Expected subscribe message, got %s", msg)
+		if matches {
+			count++
 		}
+	}
+	return count
+}
 
-		fakeTrade := `{"type":"trade","data":[{"p":100.5,"s":"AAPL"}]}`
-		c.WriteMessage(websocket.TextMessage, []byte(fakeTrade))
-	}))
-	defer mockServer.Close()
-
-	wsURL := "ws" + strings.TrimPrefix(mockServer.URL, "http")
+func TestStreamClient(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
 
 	dataChan := make(chan []byte)
-	client := NewStreamClient("fake-token", []string{"AAPL"})
-
-	client.BaseURL = wsURL
+	client := newTestStreamClient(fake, []string{"AAPL"})
+	defer client.Stop()
+
+	teed := make(chan []byte, 1)
+	client.OnFrame = func(received time.Time, frame []byte) {
+		select {
+		case teed <- frame:
+		default:
+		}
+	}
 
 	go client.Start(dataChan)
 
+	if !fake.WaitForSubscription("AAPL", time.Second) {
+		t.Fatal("Expected subscribe message for AAPL")
+	}
+	fake.SendTrades(models.Trade{Price: 100.5, Symbol: "AAPL"})
+
 	select {
 	case msg := <-dataChan:
 		if !strings.Contains(string(msg), "100.5") {
 			t.Errorf("Expected trade data 100.5, got %s", msg)
 		}
+		if frame := <-teed; string(frame) != string(msg) {
+			t.Errorf("Expected OnFrame to see %s, got %s", msg, frame)
+		}
 	case <-time.After(1 * time.Second):
 		t.Fatal("Did not receive message from mock server in time")
 	}
 }
 
 func TestStreamClientSubscription(t *testing.T) {
-	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		c, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			return
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	client := newTestStreamClient(fake, []string{})
+	defer client.Stop()
+
+	go client.Start(make(chan []byte))
+
+	if !fake.WaitForConnections(1, time.Second) {
+		t.Fatal("Expected the client to connect")
+	}
+	client.Subscribe("TSLA")
+
+	if !fake.WaitForSubscription("TSLA", time.Second) {
+		t.Errorf("Expected subscription for TSLA, got %v", fake.StreamMessages())
+	}
+}
+
+func TestStreamClientReconnect(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
+
+	var states []ConnState
+	var statesMu sync.Mutex
+
+	dataChan := make(chan []byte)
+	client := newTestStreamClient(fake, []string{"AAPL"})
+	client.OnStateChange = func(state ConnState) {
+		statesMu.Lock()
+		states = append(states, state)
+		statesMu.Unlock()
+	}
+	defer client.Stop()
+
+	go client.Start(dataChan)
+
+	if !fake.WaitForSubscription("AAPL", time.Second) {
+		t.Fatal("Expected subscribe message for AAPL")
+	}
+	client.Subscribe("TSLA")
+	if !fake.WaitForSubscription("TSLA", time.Second) {
+		t.Fatal("Expected subscribe message for TSLA")
+	}
+
+	fake.DropStreams()
+	if !fake.WaitForConnections(2, 2*time.Second) {
+		t.Fatal("Expected the client to reconnect")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for countMessages(fake.StreamMessages(), `"subscribe"`, "TSLA") < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	messages := fake.StreamMessages()
+	if countMessages(messages, `"subscribe"`, "AAPL") != 2 || countMessages(messages, `"subscribe"`, "TSLA") != 2 {
+		t.Fatalf("Expected AAPL and TSLA to be replayed after reconnect, got %v", messages)
+	}
+
+	fake.SendTrades(models.Trade{Price: 1, Symbol: "TSLA"})
+
+	select {
+	case <-dataChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Did not receive message after reconnect")
+	}
+
+	statesMu.Lock()
+	defer statesMu.Unlock()
+
+	want := []ConnState{StateConnecting, StateConnected, StateBackingOff, StateConnecting, StateConnected}
+	if len(states) < len(want) {
+		t.Fatalf("Expected at least %d state changes, got %v", len(want), states)
+	}
+	for i, state := range want {
+		if states[i] != state {
+			t.Errorf("Expected state %d to be %s, got %s", i, state, states[i])
 		}
-		defer c.Close()
+	}
+}
 
-		_, msg, _ := c.ReadMessage()
-		if !strings.Contains(string(msg), "subscribe") || !strings.Contains(string(msg), "TSLA") {
-			t.Errorf("Expected subscription for TSLA, got %s", msg)
+func TestStreamClientBackoff(t *testing.T) {
+	client := NewStreamClient("fake-token", nil)
+	client.MinBackoff = 100 * time.Millisecond
+	client.MaxBackoff = time.Second
+
+	for attempt := range 10 {
+		delay := client.backoff(attempt)
+		if delay < 50*time.Millisecond || delay > client.MaxBackoff {
+			t.Errorf("Attempt %d: backoff %s out of range", attempt, delay)
 		}
-	}))
-	defer mockServer.Close()
+	}
+
+	if delay := client.backoff(100); delay < client.MaxBackoff/2 {
+		t.Errorf("Expected large attempts to be capped near max backoff, got %s", delay)
+	}
+}
+
+func TestStreamClientUnsubscribe(t *testing.T) {
+	fake := finnhubtest.NewServer()
+	defer fake.Close()
 
-	wsURL := "ws" + strings.TrimPrefix(mockServer.URL, "http")
-	client := NewStreamClient("fake-token", []string{})
-	client.BaseURL = wsURL
+	client := newTestStreamClient(fake, []string{"AAPL"})
+	defer client.Stop()
 
 	go client.Start(make(chan []byte))
 
-	time.Sleep(50 * time.Millisecond)
-	client.Subscribe("TSLA")
+	if !fake.WaitForSubscription("AAPL", time.Second) {
+		t.Fatal("Did not receive subscribe message in time")
+	}
+
+	client.Unsubscribe("AAPL")
+	client.Unsubscribe("MSFT")
+
+	if !fake.WaitForUnsubscription("AAPL", time.Second) {
+		t.Fatal("Did not receive unsubscribe message in time")
+	}
 
 	time.Sleep(50 * time.Millisecond)
+
+	// WaitForUnsubscription also returns when the connection drops, so check
+	// the frame itself was sent.
+	if n := countMessages(fake.StreamMessages(), `"unsubscribe"`, "AAPL"); n != 1 {
+		t.Errorf("Expected 1 unsubscribe frame for AAPL, got %d", n)
+	}
+	if n := countMessages(fake.StreamMessages(), "MSFT"); n != 0 {
+		t.Errorf("Expected no frame for a symbol that was never subscribed, got %d", n)
+	}
 }
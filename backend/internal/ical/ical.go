@@ -0,0 +1,119 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+
+	// maxLineOctets is the longest content line RFC 5545 allows before it
+	// must be folded.
+	maxLineOctets = 75
+)
+
+// Event is a calendar entry. All-day events only use the date of Start and
+// ignore Duration.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	Duration    time.Duration
+	AllDay      bool
+}
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Write encodes the calendar. DTSTAMP is set to now, as RFC 5545 requires.
+func (c *Calendar) Write(w io.Writer) error {
+	return c.write(w, time.Now())
+}
+
+func (c *Calendar) write(w io.Writer, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	stamp := now.UTC().Format(dateTimeFormat)
+
+	line(bw, "BEGIN:VCALENDAR")
+	line(bw, "VERSION:2.0")
+	line(bw, "PRODID:-//co-finance//calendar//EN")
+	line(bw, "CALSCALE:GREGORIAN")
+	line(bw, "METHOD:PUBLISH")
+	if c.Name != "" {
+		line(bw, "X-WR-CALNAME:"+escape(c.Name))
+	}
+
+	for _, event := range c.Events {
+		line(bw, "BEGIN:VEVENT")
+		line(bw, "UID:"+escape(event.UID))
+		line(bw, "DTSTAMP:"+stamp)
+
+		if event.AllDay {
+			day := event.Start.Format(dateFormat)
+			next := event.Start.AddDate(0, 0, 1).Format(dateFormat)
+			line(bw, "DTSTART;VALUE=DATE:"+day)
+			line(bw, "DTEND;VALUE=DATE:"+next)
+		} else {
+			start := event.Start.UTC()
+			line(bw, "DTSTART:"+start.Format(dateTimeFormat))
+			line(bw, "DTEND:"+start.Add(event.Duration).Format(dateTimeFormat))
+		}
+
+		line(bw, "SUMMARY:"+escape(event.Summary))
+		if event.Description != "" {
+			line(bw, "DESCRIPTION:"+escape(event.Description))
+		}
+		if event.URL != "" {
+			line(bw, "URL:"+event.URL)
+		}
+		line(bw, "END:VEVENT")
+	}
+
+	line(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// line writes a CRLF-terminated content line, folding it at maxLineOctets
+// without splitting a UTF-8 sequence.
+func line(w *bufio.Writer, content string) {
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(content[cut]) {
+			cut--
+		}
+		w.WriteString(content[:cut])
+		w.WriteString("\r\n ")
+		content = content[cut:]
+
+		// Continuation lines start with a space, which counts toward the limit.
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(content)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\r", `\n`,
+	"\n", `\n`,
+)
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
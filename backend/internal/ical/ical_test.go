@@ -0,0 +1,75 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarWrite(t *testing.T) {
+	cal := Calendar{
+		Name: "Earnings",
+		Events: []Event{
+			{UID: "earnings-AAPL-2024-02-01@co-finance", Summary: "AAPL earnings, after close", Start: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), AllDay: true},
+			{UID: "cpi@co-finance", Summary: "CPI; YoY", Description: "Estimate 3.2%\nPrev 3.1%\rActual 3.4%\r\nRevised", Start: time.Date(2024, 1, 11, 13, 30, 0, 0, time.UTC), Duration: 30 * time.Minute},
+		},
+	}
+
+	var b strings.Builder
+	if err := cal.write(&b, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Earnings\r\n",
+		"DTSTAMP:20240101T120000Z\r\n",
+		"DTSTART;VALUE=DATE:20240201\r\nDTEND;VALUE=DATE:20240202\r\n",
+		"SUMMARY:AAPL earnings\\, after close\r\n",
+		"DTSTART:20240111T133000Z\r\nDTEND:20240111T140000Z\r\n",
+		"SUMMARY:CPI\\; YoY\r\n",
+		"DESCRIPTION:Estimate 3.2%\\nPrev 3.1%\\nActual 3.4%\\nRevised\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Expected 2 events, got %d", strings.Count(out, "BEGIN:VEVENT"))
+	}
+}
+
+func TestLongLinesAreFolded(t *testing.T) {
+	cal := Calendar{Events: []Event{{
+		UID:     "long@co-finance",
+		Summary: strings.Repeat("é", 100),
+		Start:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		AllDay:  true,
+	}}}
+
+	var b strings.Builder
+	cal.Write(&b)
+
+	var summary strings.Builder
+	inSummary := false
+	for _, l := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(l) > maxLineOctets {
+			t.Errorf("Expected lines of at most %d octets, got %d", maxLineOctets, len(l))
+		}
+		switch {
+		case strings.HasPrefix(l, "SUMMARY:"):
+			inSummary = true
+			summary.WriteString(strings.TrimPrefix(l, "SUMMARY:"))
+		case inSummary && strings.HasPrefix(l, " "):
+			summary.WriteString(l[1:])
+		default:
+			inSummary = false
+		}
+	}
+
+	if summary.String() != strings.Repeat("é", 100) {
+		t.Errorf("Expected the folded summary to unfold intact, got %q", summary.String())
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Bars zips the parallel candle arrays into bars. Finnhub timestamps are unix
+// seconds and are returned in UTC.
+func (c *Candles) Bars() ([]Bar, error) {
+	n := len(c.Timestamp)
+	for _, values := range [][]float64{c.Open, c.High, c.Low, c.Close, c.Volume} {
+		if len(values) != n {
+			return nil, fmt.Errorf("candles: mismatched array lengths (%d timestamps, %d values)", n, len(values))
+		}
+	}
+
+	bars := make([]Bar, n)
+	for i := range bars {
+		bars[i] = Bar{
+			Time:   time.Unix(c.Timestamp[i], 0).UTC(),
+			Open:   c.Open[i],
+			High:   c.High[i],
+			Low:    c.Low[i],
+			Close:  c.Close[i],
+			Volume: c.Volume[i],
+		}
+	}
+	return bars, nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCandlesBars(t *testing.T) {
+	var candles Candles
+	data := `{"c":[217.68,221.03],"h":[222.49,221.5],"l":[217.19,217.1402],"o":[221.03,218.55],"s":"ok","t":[1569297600,1569384000],"v":[33463820,24018876]}`
+	if err := json.Unmarshal([]byte(data), &candles); err != nil {
+		t.Fatal(err)
+	}
+
+	bars, err := candles.Bars()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(bars) != 2 {
+		t.Fatalf("Expected 2 bars, got %d", len(bars))
+	}
+
+	want := Bar{Time: time.Date(2019, 9, 25, 4, 0, 0, 0, time.UTC), Open: 218.55, High: 221.5, Low: 217.1402, Close: 221.03, Volume: 24018876}
+	if bars[1] != want {
+		t.Errorf("Expected %+v, got %+v", want, bars[1])
+	}
+}
+
+func TestCandlesBarsMismatchedLengths(t *testing.T) {
+	candles := Candles{Close: []float64{1, 2}, High: []float64{1}, Low: []float64{1}, Open: []float64{1}, Timestamp: []int64{1}, Volume: []float64{1}}
+
+	if _, err := candles.Bars(); err == nil {
+		t.Error("Expected error for mismatched arrays")
+	}
+}
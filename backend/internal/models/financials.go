@@ -0,0 +1,130 @@
+package models
+
+import "encoding/json"
+
+// coreMetrics are the metrics the dashboard always shows. They decode as 0
+// when Finnhub omits them or reports null, like the typed fields they
+// replaced.
+var coreMetrics = []string{
+	"peBasicExclExtraTTM",
+	"marketCapitalization",
+	"52WeekHigh",
+	"52WeekLow",
+	"dividendYieldIndicatedAnnual",
+	"beta",
+}
+
+// Metrics is the full metric map Finnhub returns. Values are mostly numbers
+// but some are dates or null.
+type Metrics map[string]any
+
+// Float returns a numeric metric, and false when it is missing or not a
+// number.
+func (m Metrics) Float(name string) (float64, bool) {
+	switch v := m[name].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func (f *BasicFinancials) UnmarshalJSON(data []byte) error {
+	type plain BasicFinancials
+	if err := json.Unmarshal(data, (*plain)(f)); err != nil {
+		return err
+	}
+
+	if f.Metric == nil {
+		f.Metric = make(Metrics, len(coreMetrics))
+	}
+	for _, name := range coreMetrics {
+		if _, ok := f.Metric.Float(name); !ok {
+			f.Metric[name] = 0.0
+		}
+	}
+	if f.Series.Annual == nil {
+		f.Series.Annual = make(map[string][]SeriesPoint)
+	}
+
+	return nil
+}
+
+func (m Metrics) value(name string) float64 {
+	v, _ := m.Float(name)
+	return v
+}
+
+func (f *BasicFinancials) PeBasicExclExtraTTM() float64 {
+	return f.Metric.value("peBasicExclExtraTTM")
+}
+
+func (f *BasicFinancials) MarketCapitalization() float64 {
+	return f.Metric.value("marketCapitalization")
+}
+
+func (f *BasicFinancials) High52Week() float64 {
+	return f.Metric.value("52WeekHigh")
+}
+
+func (f *BasicFinancials) Low52Week() float64 {
+	return f.Metric.value("52WeekLow")
+}
+
+func (f *BasicFinancials) DividendYield() float64 {
+	return f.Metric.value("dividendYieldIndicatedAnnual")
+}
+
+func (f *BasicFinancials) Beta() float64 {
+	return f.Metric.value("beta")
+}
+
+func (f *BasicFinancials) AnnualSeries(name string) []SeriesPoint {
+	return f.Series.Annual[name]
+}
+
+func (f *BasicFinancials) QuarterlySeries(name string) []SeriesPoint {
+	return f.Series.Quarterly[name]
+}
+
+// Select returns a copy limited to the named metrics and series. A nil list
+// keeps everything of that kind; names Finnhub did not return are skipped.
+// Selected series come from both the annual and quarterly data.
+func (f *BasicFinancials) Select(metrics, series []string) *BasicFinancials {
+	selected := &BasicFinancials{
+		Series:     f.Series,
+		Metric:     f.Metric,
+		MetricType: f.MetricType,
+		Symbol:     f.Symbol,
+	}
+
+	if metrics != nil {
+		selected.Metric = make(Metrics, len(metrics))
+		for _, name := range metrics {
+			if v, ok := f.Metric[name]; ok {
+				selected.Metric[name] = v
+			}
+		}
+	}
+
+	if series != nil {
+		selected.Series = FinancialSeries{
+			Annual:    selectSeries(f.Series.Annual, series),
+			Quarterly: selectSeries(f.Series.Quarterly, series),
+		}
+	}
+
+	return selected
+}
+
+func selectSeries(all map[string][]SeriesPoint, names []string) map[string][]SeriesPoint {
+	selected := make(map[string][]SeriesPoint, len(names))
+	for _, name := range names {
+		if points, ok := all[name]; ok {
+			selected[name] = points
+		}
+	}
+	return selected
+}
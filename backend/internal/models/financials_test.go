@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const financialsJSON = `{
+	"metric": {
+		"52WeekHigh": 199.62,
+		"52WeekHighDate": "2023-12-14",
+		"beta": null,
+		"currentRatioQuarterly": 0.988,
+		"peBasicExclExtraTTM": 29.47
+	},
+	"metricType": "all",
+	"series": {
+		"annual": {
+			"currentRatio": [{"period": "2023-09-30", "v": 0.988}],
+			"netMargin": [{"period": "2023-09-30", "v": 0.2531}]
+		},
+		"quarterly": {
+			"currentRatio": [{"period": "2023-09-30", "v": 0.988}, {"period": "2023-06-30", "v": 0.9805}]
+		}
+	},
+	"symbol": "AAPL"
+}`
+
+func TestBasicFinancialsDecode(t *testing.T) {
+	var financials BasicFinancials
+	if err := json.Unmarshal([]byte(financialsJSON), &financials); err != nil {
+		t.Fatal(err)
+	}
+
+	if financials.High52Week() != 199.62 || financials.PeBasicExclExtraTTM() != 29.47 {
+		t.Errorf("Expected typed metrics, got %v", financials.Metric)
+	}
+	if v, ok := financials.Metric.Float("currentRatioQuarterly"); !ok || v != 0.988 {
+		t.Errorf("Expected currentRatioQuarterly 0.988, got %v", v)
+	}
+	if _, ok := financials.Metric.Float("52WeekHighDate"); ok {
+		t.Error("Expected date metric not to be a number")
+	}
+	if v, ok := financials.Metric.Float("beta"); !ok || v != 0 {
+		t.Errorf("Expected null beta to decode as 0, got %v", financials.Metric["beta"])
+	}
+	if len(financials.QuarterlySeries("currentRatio")) != 2 || len(financials.AnnualSeries("netMargin")) != 1 {
+		t.Errorf("Expected annual and quarterly series, got %+v", financials.Series)
+	}
+
+	data, err := json.Marshal(&financials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"Series":{"Annual":{`, `"Quarterly":{`, `"dividendYieldIndicatedAnnual":0`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("Expected %s in %s", key, data)
+		}
+	}
+}
+
+func TestBasicFinancialsSelect(t *testing.T) {
+	var financials BasicFinancials
+	if err := json.Unmarshal([]byte(financialsJSON), &financials); err != nil {
+		t.Fatal(err)
+	}
+
+	selected := financials.Select([]string{"52WeekHigh", "missing"}, []string{"currentRatio"})
+
+	if len(selected.Metric) != 1 || selected.High52Week() != 199.62 {
+		t.Errorf("Expected only 52WeekHigh, got %v", selected.Metric)
+	}
+	if len(selected.Series.Annual) != 1 || len(selected.QuarterlySeries("currentRatio")) != 2 {
+		t.Errorf("Expected only currentRatio series, got %+v", selected.Series)
+	}
+	if len(financials.Metric) != 8 || len(financials.Series.Annual) != 2 {
+		t.Error("Expected Select to leave the original untouched")
+	}
+
+	all := financials.Select(nil, []string{"netMargin"})
+	if len(all.Metric) != len(financials.Metric) || all.QuarterlySeries("netMargin") != nil {
+		t.Errorf("Expected every metric and only netMargin series, got %+v", all)
+	}
+}
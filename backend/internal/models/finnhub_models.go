@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // https://finnhub.io/docs/api/quote
 type StockQuote struct {
 	CurrentPrice  float64 `json:"c"`
@@ -10,36 +12,129 @@ type StockQuote struct {
 	OpenPrice     float64 `json:"o"`
 	PrevClose     float64 `json:"pc"`
 	Timestamp     float64 `json:"t"`
+	Source        string  `json:"source,omitempty"`
 }
 
 // https://finnhub.io/docs/api/company-basic-financials
 type BasicFinancials struct {
-	Series struct {
-		Annual struct {
-			CurrentRatio []struct {
-				Period string  `json:"period"`
-				V      float64 `json:"v"`
-			} `json:"currentRatio"`
-			SalesPerShare []struct {
-				Period string  `json:"period"`
-				V      float64 `json:"v"`
-			} `json:"salesPerShare"`
-			NetMargin []struct {
-				Period string  `json:"period"`
-				V      float64 `json:"v"`
-			} `json:"netMargin"`
-		}
-	} `json:"Series"`
-	Metric struct {
-		PeBasicExclExtraTTM  float64 `json:"peBasicExclExtraTTM"`
-		MarketCapitalization float64 `json:"marketCapitalization"`
-		High52Week           float64 `json:"52WeekHigh"`
-		Low52Week            float64 `json:"52WeekLow"`
-		DividendYield        float64 `json:"dividendYieldIndicatedAnnual"`
-		Beta                 float64 `json:"beta"`
-	} `json:"metric"`
-	MetricType string `json:"metricType"`
-	Symbol     string `json:"symbol"`
+	Series     FinancialSeries `json:"Series"`
+	Metric     Metrics         `json:"metric"`
+	MetricType string          `json:"metricType"`
+	Symbol     string          `json:"symbol"`
+}
+
+// FinancialSeries holds ratio histories keyed by metric name, e.g.
+// "currentRatio".
+type FinancialSeries struct {
+	Annual    map[string][]SeriesPoint `json:"Annual"`
+	Quarterly map[string][]SeriesPoint `json:"Quarterly,omitempty"`
+}
+
+type SeriesPoint struct {
+	Period string  `json:"period"`
+	V      float64 `json:"v"`
+}
+
+// https://finnhub.io/docs/api/company-profile2
+type CompanyProfile struct {
+	Country              string  `json:"country"`
+	Currency             string  `json:"currency"`
+	Exchange             string  `json:"exchange"`
+	FinnhubIndustry      string  `json:"finnhubIndustry"`
+	Ipo                  string  `json:"ipo"`
+	Logo                 string  `json:"logo"`
+	MarketCapitalization float64 `json:"marketCapitalization"`
+	Name                 string  `json:"name"`
+	Phone                string  `json:"phone"`
+	ShareOutstanding     float64 `json:"shareOutstanding"`
+	Ticker               string  `json:"ticker"`
+	Weburl               string  `json:"weburl"`
+}
+
+// https://finnhub.io/docs/api/symbol-search
+type SymbolLookup struct {
+	Count  int                  `json:"count"`
+	Result []SymbolLookupResult `json:"result"`
+}
+
+type SymbolLookupResult struct {
+	Description   string `json:"description"`
+	DisplaySymbol string `json:"displaySymbol"`
+	Symbol        string `json:"symbol"`
+	Type          string `json:"type"`
+}
+
+// https://finnhub.io/docs/api/stock-symbols
+type StockSymbol struct {
+	Currency      string `json:"currency"`
+	Description   string `json:"description"`
+	DisplaySymbol string `json:"displaySymbol"`
+	Figi          string `json:"figi"`
+	Mic           string `json:"mic"`
+	Symbol        string `json:"symbol"`
+	Type          string `json:"type"`
+}
+
+// https://finnhub.io/docs/api/stock-candles
+type Candles struct {
+	Close     []float64 `json:"c"`
+	High      []float64 `json:"h"`
+	Low       []float64 `json:"l"`
+	Open      []float64 `json:"o"`
+	Status    string    `json:"s"`
+	Timestamp []int64   `json:"t"`
+	Volume    []float64 `json:"v"`
+}
+
+// https://finnhub.io/docs/api/earnings-calendar
+// Actuals are nil until the company reports.
+type EarningsRelease struct {
+	Date            string   `json:"date"`
+	EpsActual       *float64 `json:"epsActual"`
+	EpsEstimate     *float64 `json:"epsEstimate"`
+	Hour            string   `json:"hour"`
+	Quarter         int      `json:"quarter"`
+	RevenueActual   *float64 `json:"revenueActual"`
+	RevenueEstimate *float64 `json:"revenueEstimate"`
+	Symbol          string   `json:"symbol"`
+	Year            int      `json:"year"`
+}
+
+type EarningsCalendar struct {
+	EarningsCalendar []EarningsRelease `json:"earningsCalendar"`
+}
+
+// https://finnhub.io/docs/api/ipo-calendar
+type IPOEvent struct {
+	Date             string   `json:"date"`
+	Exchange         string   `json:"exchange"`
+	Name             string   `json:"name"`
+	NumberOfShares   *float64 `json:"numberOfShares"`
+	Price            string   `json:"price"`
+	Status           string   `json:"status"`
+	Symbol           string   `json:"symbol"`
+	TotalSharesValue *float64 `json:"totalSharesValue"`
+}
+
+type IPOCalendar struct {
+	IPOCalendar []IPOEvent `json:"ipoCalendar"`
+}
+
+// https://finnhub.io/docs/api/economic-calendar
+// Time is "YYYY-MM-DD HH:MM:SS" in UTC.
+type EconomicEvent struct {
+	Actual   *float64 `json:"actual"`
+	Country  string   `json:"country"`
+	Estimate *float64 `json:"estimate"`
+	Event    string   `json:"event"`
+	Impact   string   `json:"impact"`
+	Prev     *float64 `json:"prev"`
+	Time     string   `json:"time"`
+	Unit     string   `json:"unit"`
+}
+
+type EconomicCalendar struct {
+	EconomicCalendar []EconomicEvent `json:"economicCalendar"`
 }
 
 // https://finnhub.io/docs/api/company-earnings
@@ -101,3 +196,30 @@ type MarketStatus struct {
 	Timestamp int64   `json:"t"`
 	Timezone  string  `json:"timezone"`
 }
+
+// https://finnhub.io/docs/api/websocket-trades
+type Trade struct {
+	Price      float64  `json:"p"`
+	Symbol     string   `json:"s"`
+	Timestamp  int64    `json:"t"`
+	Volume     float64  `json:"v"`
+	Conditions []string `json:"c,omitempty"`
+}
+
+func (t Trade) Time() time.Time {
+	return time.UnixMilli(t.Timestamp).UTC()
+}
+
+type TradeMessage struct {
+	Type string  `json:"type"`
+	Data []Trade `json:"data"`
+}
+
+type Bar struct {
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
@@ -0,0 +1,65 @@
+package news
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+// Dedupe drops stories already seen earlier in items, matching on Id or on
+// the normalized URL since Finnhub sometimes republishes a story under a new
+// id. The first occurrence wins and order is preserved.
+func Dedupe(items []models.CompanyNews) []models.CompanyNews {
+	ids := make(map[int64]bool, len(items))
+	urls := make(map[string]bool, len(items))
+
+	unique := make([]models.CompanyNews, 0, len(items))
+	for _, item := range items {
+		key := NormalizeURL(item.Url)
+		if (item.Id != 0 && ids[item.Id]) || (key != "" && urls[key]) {
+			continue
+		}
+
+		if item.Id != 0 {
+			ids[item.Id] = true
+		}
+		if key != "" {
+			urls[key] = true
+		}
+		unique = append(unique, item)
+	}
+
+	return unique
+}
+
+// NormalizeURL reduces a story URL to what identifies it: the scheme and
+// host are lower-cased, and the fragment, tracking parameters and any
+// trailing slash are dropped. Other query parameters are kept because
+// Finnhub's own links identify stories by them.
+func NormalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	query := u.Query()
+	for param := range query {
+		if strings.HasPrefix(strings.ToLower(param), "utm_") {
+			query.Del(param)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
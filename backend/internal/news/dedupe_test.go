@@ -0,0 +1,55 @@
+package news
+
+import (
+	"testing"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+func TestDedupe(t *testing.T) {
+	items := []models.CompanyNews{
+		{Id: 1, Headline: "first", Url: "https://finnhub.io/api/news?id=aaa"},
+		{Id: 2, Headline: "other story", Url: "https://finnhub.io/api/news?id=bbb"},
+		{Id: 1, Headline: "same id", Url: "https://finnhub.io/api/news?id=ccc"},
+		{Id: 3, Headline: "same url", Url: "https://FINNHUB.io/api/news?id=aaa&utm_source=feed#top"},
+		{Headline: "no id", Url: "https://example.com/story/"},
+		{Headline: "no id again", Url: "https://example.com/story"},
+		{Id: 4, Headline: "no url"},
+		{Id: 5, Headline: "no url either"},
+	}
+
+	unique := Dedupe(items)
+
+	var headlines []string
+	for _, item := range unique {
+		headlines = append(headlines, item.Headline)
+	}
+
+	want := []string{"first", "other story", "no id", "no url", "no url either"}
+	if len(headlines) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, headlines)
+	}
+	for i := range want {
+		if headlines[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, headlines)
+			break
+		}
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://finnhub.io/api/news?id=abc", "https://finnhub.io/api/news?id=abc"},
+		{"HTTPS://Example.com/a/?utm_medium=rss&page=2#c", "https://example.com/a?page=2"},
+		{"", ""},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.in); got != tt.want {
+			t.Errorf("Expected NormalizeURL(%q) = %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
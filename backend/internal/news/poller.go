@@ -0,0 +1,233 @@
+package news
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+const (
+	DefaultPollInterval = time.Minute
+
+	// pollWindow is how far back company news is requested on each poll. It
+	// spans two calendar days so stories published just before midnight UTC
+	// are still seen.
+	pollWindow  = 24 * time.Hour
+	pollTimeout = 30 * time.Second
+
+	generalCategory = "general"
+)
+
+type Source interface {
+	GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error)
+	GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error)
+}
+
+// Update is a batch of stories that were not there on the previous poll.
+// Symbol is empty for general market news.
+type Update struct {
+	Symbol string
+	Items  []models.CompanyNews
+}
+
+// feed tracks the stories already seen for one symbol, or for general news.
+type feed struct {
+	ids   map[int64]time.Time
+	urls  map[string]time.Time
+	minID int64
+}
+
+func newFeed() *feed {
+	return &feed{ids: make(map[int64]time.Time), urls: make(map[string]time.Time)}
+}
+
+// Poller periodically fetches company news for the symbols returned by
+// Symbols, and general market news, and passes stories it has not seen
+// before to Publish. The first poll of a feed only records what is there,
+// since clients load the current news over REST.
+type Poller struct {
+	Interval time.Duration
+	Symbols  func() []string
+	Publish  func(Update)
+
+	// Context wraps the context of each request, e.g. to lower its rate
+	// limiter priority.
+	Context func(context.Context) context.Context
+
+	// Registry, when set, gives pushed stories the ids the REST endpoints
+	// serve them under.
+	Registry *Registry
+
+	source   Source
+	feeds    map[string]*feed
+	now      func() time.Time
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewPoller(source Source, symbols func() []string, publish func(Update)) *Poller {
+	return &Poller{
+		Interval: DefaultPollInterval,
+		Symbols:  symbols,
+		Publish:  publish,
+		source:   source,
+		feeds:    make(map[string]*feed),
+		now:      time.Now,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start polls right away and then every Interval until Stop is called.
+func (p *Poller) Start() {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.Poll()
+
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// Poll fetches every feed once. Feeds of symbols that are no longer
+// subscribed are forgotten, so a later subscription starts afresh.
+func (p *Poller) Poll() {
+	symbols := p.Symbols()
+
+	active := make(map[string]bool, len(symbols)+1)
+	active[""] = true
+	for _, symbol := range symbols {
+		active[symbol] = true
+	}
+	for symbol := range p.feeds {
+		if !active[symbol] {
+			delete(p.feeds, symbol)
+		}
+	}
+
+	p.pollGeneral()
+	for _, symbol := range symbols {
+		p.pollCompany(symbol)
+	}
+}
+
+func (p *Poller) pollGeneral() {
+	f, known := p.feed("")
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	items, err := p.source.GetMarketNewsContext(ctx, generalCategory, f.minID)
+	if err != nil {
+		log.Printf("News poll failed for general news: %v", err)
+		return
+	}
+
+	for _, item := range items {
+		f.minID = max(f.minID, item.Id)
+	}
+	p.publish("", f, known, items)
+}
+
+func (p *Poller) pollCompany(symbol string) {
+	f, known := p.feed(symbol)
+
+	now := p.now().UTC()
+	from := now.Add(-pollWindow).Format(time.DateOnly)
+	to := now.Format(time.DateOnly)
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	items, err := p.source.GetCompanyNewsContext(ctx, symbol, from, to)
+	if err != nil {
+		log.Printf("News poll failed for %s: %v", symbol, err)
+		return
+	}
+
+	p.publish(symbol, f, known, items)
+}
+
+// feed returns the symbol's feed and whether it has been polled before.
+func (p *Poller) feed(symbol string) (*feed, bool) {
+	if f, ok := p.feeds[symbol]; ok {
+		return f, true
+	}
+	return newFeed(), false
+}
+
+func (p *Poller) context() (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if p.Context != nil {
+		ctx = p.Context(ctx)
+	}
+	return context.WithTimeout(ctx, pollTimeout)
+}
+
+// publish records items as seen and publishes the unseen ones, unless this
+// is the feed's first successful poll.
+func (p *Poller) publish(symbol string, f *feed, known bool, items []models.CompanyNews) {
+	now := p.now()
+	p.feeds[symbol] = f
+
+	if p.Registry != nil {
+		feed := CompanyFeed(symbol)
+		if symbol == "" {
+			feed = MarketFeed(generalCategory)
+		}
+		items = p.Registry.Canonicalize(feed, items)
+	} else {
+		items = Dedupe(items)
+	}
+
+	var fresh []models.CompanyNews
+	for _, item := range items {
+		key := NormalizeURL(item.Url)
+		_, seenID := f.ids[item.Id]
+		_, seenURL := f.urls[key]
+		if (item.Id != 0 && seenID) || (key != "" && seenURL) {
+			continue
+		}
+
+		if item.Id != 0 {
+			f.ids[item.Id] = now
+		}
+		if key != "" {
+			f.urls[key] = now
+		}
+		fresh = append(fresh, item)
+	}
+	f.prune(now.Add(-2 * pollWindow))
+
+	if known && len(fresh) > 0 {
+		p.Publish(Update{Symbol: symbol, Items: fresh})
+	}
+}
+
+// prune forgets stories first seen before cutoff. They have left the poll
+// window by then, so they cannot come back as new.
+func (f *feed) prune(cutoff time.Time) {
+	for id, seen := range f.ids {
+		if seen.Before(cutoff) {
+			delete(f.ids, id)
+		}
+	}
+	for url, seen := range f.urls {
+		if seen.Before(cutoff) {
+			delete(f.urls, url)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+type fakeSource struct {
+	company map[string][]models.CompanyNews
+	general []models.CompanyNews
+	err     error
+	minIDs  []int64
+	ranges  [][2]string
+}
+
+func (f *fakeSource) GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error) {
+	f.ranges = append(f.ranges, [2]string{from, to})
+	return f.company[symbol], f.err
+}
+
+func (f *fakeSource) GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error) {
+	f.minIDs = append(f.minIDs, minID)
+
+	var items []models.CompanyNews
+	for _, item := range f.general {
+		if item.Id > minID {
+			items = append(items, item)
+		}
+	}
+	return items, f.err
+}
+
+func story(id int64, url string) models.CompanyNews {
+	return models.CompanyNews{Id: id, Headline: url, Url: url}
+}
+
+func newTestPoller(source Source, symbols ...string) (*Poller, *[]Update) {
+	var updates []Update
+	p := NewPoller(source, func() []string { return symbols }, func(u Update) {
+		updates = append(updates, u)
+	})
+	p.now = func() time.Time { return time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC) }
+	return p, &updates
+}
+
+func TestPollerPublishesOnlyNewStories(t *testing.T) {
+	source := &fakeSource{company: map[string][]models.CompanyNews{
+		"AAPL": {story(1, "https://example.com/a")},
+	}}
+	p, updates := newTestPoller(source, "AAPL")
+
+	p.Poll()
+	if len(*updates) != 0 {
+		t.Fatalf("Expected the first poll to publish nothing, got %+v", *updates)
+	}
+	if source.ranges[0] != [2]string{"2024-03-04", "2024-03-05"} {
+		t.Errorf("Expected the last two days, got %v", source.ranges[0])
+	}
+
+	source.company["AAPL"] = []models.CompanyNews{
+		story(3, "https://example.com/c"),
+		story(2, "https://example.com/a?utm_source=feed"),
+		story(1, "https://example.com/a"),
+	}
+	p.Poll()
+
+	if len(*updates) != 1 {
+		t.Fatalf("Expected 1 update, got %+v", *updates)
+	}
+	got := (*updates)[0]
+	if got.Symbol != "AAPL" || len(got.Items) != 1 || got.Items[0].Id != 3 {
+		t.Errorf("Expected only story 3 for AAPL, got %+v", got)
+	}
+
+	p.Poll()
+	if len(*updates) != 1 {
+		t.Errorf("Expected nothing new on an unchanged poll, got %+v", *updates)
+	}
+}
+
+func TestPollerGeneralNewsUsesMinID(t *testing.T) {
+	source := &fakeSource{general: []models.CompanyNews{story(10, "https://example.com/10")}}
+	p, updates := newTestPoller(source)
+
+	p.Poll()
+	source.general = append(source.general, story(11, "https://example.com/11"))
+	p.Poll()
+
+	if source.minIDs[0] != 0 || source.minIDs[1] != 10 {
+		t.Errorf("Expected minId 0 then 10, got %v", source.minIDs)
+	}
+	if len(*updates) != 1 || (*updates)[0].Symbol != "" || (*updates)[0].Items[0].Id != 11 {
+		t.Errorf("Expected story 11 as general news, got %+v", *updates)
+	}
+}
+
+func TestPollerForgetsUnsubscribedSymbols(t *testing.T) {
+	source := &fakeSource{company: map[string][]models.CompanyNews{
+		"AAPL": {story(1, "https://example.com/a")},
+	}}
+	symbols := []string{"AAPL"}
+	p, updates := newTestPoller(source)
+	p.Symbols = func() []string { return symbols }
+
+	p.Poll()
+	symbols = nil
+	p.Poll()
+	if _, ok := p.feeds["AAPL"]; ok {
+		t.Error("Expected the AAPL feed to be dropped")
+	}
+
+	// Resubscribing starts a new baseline instead of replaying the backlog.
+	symbols = []string{"AAPL"}
+	source.company["AAPL"] = append(source.company["AAPL"], story(2, "https://example.com/b"))
+	p.Poll()
+	if len(*updates) != 0 {
+		t.Errorf("Expected no updates, got %+v", *updates)
+	}
+}
+
+func TestPollerFailedFirstPollIsNotABaseline(t *testing.T) {
+	source := &fakeSource{err: errors.New("upstream down")}
+	p, updates := newTestPoller(source, "AAPL")
+
+	p.Poll()
+	source.err = nil
+	source.company = map[string][]models.CompanyNews{"AAPL": {story(1, "https://example.com/a")}}
+	source.general = []models.CompanyNews{story(10, "https://example.com/10")}
+	p.Poll()
+
+	if len(*updates) != 0 {
+		t.Errorf("Expected the first successful poll to publish nothing, got %+v", *updates)
+	}
+}
+
+func TestPollerUsesRegistryIDs(t *testing.T) {
+	registry := NewRegistry(DefaultRegistrySize)
+	registry.Canonicalize(CompanyFeed("AAPL"), []models.CompanyNews{story(1, "https://example.com/a")})
+
+	source := &fakeSource{company: map[string][]models.CompanyNews{}}
+	p, updates := newTestPoller(source, "AAPL")
+	p.Registry = registry
+
+	p.Poll()
+	source.company["AAPL"] = []models.CompanyNews{story(4, "https://example.com/a")}
+	p.Poll()
+
+	if len(*updates) != 1 || (*updates)[0].Items[0].Id != 1 {
+		t.Errorf("Expected the story under the id REST served, got %+v", *updates)
+	}
+}
+
+func TestPollerPrunesOldStories(t *testing.T) {
+	source := &fakeSource{company: map[string][]models.CompanyNews{
+		"AAPL": {story(1, "https://example.com/a")},
+	}}
+	p, _ := newTestPoller(source, "AAPL")
+	p.Poll()
+
+	now := p.now()
+	p.now = func() time.Time { return now.Add(3 * pollWindow) }
+	source.company["AAPL"] = nil
+	p.Poll()
+
+	if f := p.feeds["AAPL"]; len(f.ids) != 0 || len(f.urls) != 0 {
+		t.Errorf("Expected old stories to be forgotten, got %d ids and %d urls", len(f.ids), len(f.urls))
+	}
+}
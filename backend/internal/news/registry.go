@@ -0,0 +1,89 @@
+package news
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+const DefaultRegistrySize = 10000
+
+// Registry remembers the first id each story was seen under, per feed, so a
+// story Finnhub republishes under a new id in a later response keeps the id
+// clients already have. It holds at most size stories, forgetting the least
+// recently seen.
+type Registry struct {
+	size    int
+	order   *list.List
+	stories map[string]*list.Element
+	mu      sync.Mutex
+}
+
+type registered struct {
+	key string
+	id  int64
+}
+
+func NewRegistry(size int) *Registry {
+	return &Registry{
+		size:    size,
+		order:   list.New(),
+		stories: make(map[string]*list.Element),
+	}
+}
+
+// CompanyFeed and MarketFeed name the feeds stories are registered under.
+func CompanyFeed(symbol string) string {
+	return "company:" + symbol
+}
+
+func MarketFeed(category string) string {
+	return "market:" + category
+}
+
+// Canonicalize dedupes items and replaces the id of every story already
+// registered for feed with the id it was first seen under. items is not
+// modified.
+func (r *Registry) Canonicalize(feed string, items []models.CompanyNews) []models.CompanyNews {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unique := Dedupe(items)
+	canonical := unique[:0]
+	ids := make(map[int64]bool, len(unique))
+
+	for _, item := range unique {
+		if url := NormalizeURL(item.Url); url != "" {
+			item.Id = r.register(feed+"\x00"+url, item.Id)
+		}
+		if item.Id != 0 && ids[item.Id] {
+			continue
+		}
+
+		ids[item.Id] = true
+		canonical = append(canonical, item)
+	}
+
+	return canonical
+}
+
+// register must be called with r.mu held. It returns the id key was first
+// seen under, recording id if key is new.
+func (r *Registry) register(key string, id int64) int64 {
+	if el, ok := r.stories[key]; ok {
+		r.order.MoveToFront(el)
+		return el.Value.(*registered).id
+	}
+	if id == 0 {
+		return id
+	}
+
+	r.stories[key] = r.order.PushFront(&registered{key: key, id: id})
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.stories, oldest.Value.(*registered).key)
+	}
+	return id
+}
@@ -0,0 +1,57 @@
+package news
+
+import (
+	"testing"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+func TestRegistryKeepsFirstID(t *testing.T) {
+	r := NewRegistry(10)
+
+	first := r.Canonicalize(CompanyFeed("AAPL"), []models.CompanyNews{story(1, "https://example.com/a")})
+	if len(first) != 1 || first[0].Id != 1 {
+		t.Fatalf("Expected story 1, got %+v", first)
+	}
+
+	items := []models.CompanyNews{story(7, "https://Example.com/a#top"), story(8, "https://example.com/b")}
+	got := r.Canonicalize(CompanyFeed("AAPL"), items)
+	if len(got) != 2 || got[0].Id != 1 || got[1].Id != 8 {
+		t.Errorf("Expected ids 1 and 8, got %+v", got)
+	}
+	if items[0].Id != 7 {
+		t.Error("Expected the input to be left untouched")
+	}
+
+	if got := r.Canonicalize(CompanyFeed("MSFT"), []models.CompanyNews{story(7, "https://example.com/a")}); got[0].Id != 7 {
+		t.Errorf("Expected feeds to be independent, got %+v", got)
+	}
+}
+
+func TestRegistryDropsStoriesSharingACanonicalID(t *testing.T) {
+	r := NewRegistry(10)
+	r.Canonicalize(MarketFeed("general"), []models.CompanyNews{story(1, "https://example.com/a")})
+
+	got := r.Canonicalize(MarketFeed("general"), []models.CompanyNews{
+		story(1, "https://example.com/a-moved"),
+		story(2, "https://example.com/a"),
+	})
+	if len(got) != 1 || got[0].Id != 1 {
+		t.Errorf("Expected a single story 1, got %+v", got)
+	}
+}
+
+func TestRegistryIsBounded(t *testing.T) {
+	r := NewRegistry(2)
+	feed := CompanyFeed("AAPL")
+
+	r.Canonicalize(feed, []models.CompanyNews{story(1, "https://example.com/a")})
+	r.Canonicalize(feed, []models.CompanyNews{story(2, "https://example.com/b"), story(3, "https://example.com/c")})
+
+	if len(r.stories) != 2 {
+		t.Errorf("Expected 2 registered stories, got %d", len(r.stories))
+	}
+	if got := r.Canonicalize(feed, []models.CompanyNews{story(9, "https://example.com/a")}); got[0].Id != 9 {
+		t.Errorf("Expected the oldest story to be forgotten, got %+v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package offline
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decodeCSV maps each record onto a T, matching header columns to the JSON
+// tags of T's fields. Unknown columns and empty cells are ignored.
+func decodeCSV[T any](r io.Reader) ([]T, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []T{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fields := fieldIndex(reflect.TypeFor[T]())
+	columns := make([][]int, len(header))
+	for i, name := range header {
+		columns[i] = fields[strings.TrimSpace(name)]
+	}
+
+	rows := []T{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var row T
+		value := reflect.ValueOf(&row).Elem()
+		for i, cell := range record {
+			if i >= len(columns) || columns[i] == nil || cell == "" {
+				continue
+			}
+			if err := setField(value.FieldByIndex(columns[i]), cell); err != nil {
+				line, _ := reader.FieldPos(i)
+				return nil, fmt.Errorf("line %d, column %q: %w", line, header[i], err)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func fieldIndex(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for _, field := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		fields[name] = field.Index
+	}
+	return fields
+}
+
+func setField(field reflect.Value, cell string) error {
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setField(ptr.Elem(), cell); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(cell)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
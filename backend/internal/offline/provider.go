@@ -0,0 +1,388 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+	"github.com/rinz5/co-finance/backend/internal/provider"
+)
+
+var _ provider.MarketDataProvider = (*Provider)(nil)
+
+// Provider serves market data from a directory of fixture files so the server
+// can run without reaching Finnhub. Per-symbol data lives in
+// <dir>/<SYMBOL>/<name>.json or <name>.csv, where name is one of quote,
+// financials, earnings, recommendations, insiders, news, profile or peers.
+// Market status is read from <dir>/market-status/<EXCHANGE>.json, symbol
+// listings from <dir>/symbols/<EXCHANGE>.json, calendars from
+// <dir>/calendar/{earnings,ipo,economic}.json and general news from
+// <dir>/news/<category>.json. The JSON files are shaped like the models
+// types; CSV files have a header row of the same JSON field names. Financials,
+// candles, profile and peers are JSON only.
+type Provider struct {
+	dir string
+}
+
+func NewProvider(dir string) *Provider {
+	return &Provider{dir: dir}
+}
+
+func (p *Provider) GetQuoteContext(ctx context.Context, symbol string) (*models.StockQuote, error) {
+	quotes, err := load[models.StockQuote](p, symbol, "quote")
+	if err != nil {
+		return nil, err
+	}
+	if len(quotes) == 0 {
+		return nil, notFound(symbol, "quote")
+	}
+	return &quotes[len(quotes)-1], nil
+}
+
+func (p *Provider) GetBasicFinancialsContext(ctx context.Context, symbol string) (*models.BasicFinancials, error) {
+	var financials models.BasicFinancials
+	if err := p.readSymbolJSON(symbol, "financials", &financials); err != nil {
+		return nil, err
+	}
+	return &financials, nil
+}
+
+func (p *Provider) GetEarningsContext(ctx context.Context, symbol string) ([]models.EarningsSurprise, error) {
+	return load[models.EarningsSurprise](p, symbol, "earnings")
+}
+
+func (p *Provider) GetRecommendationsContext(ctx context.Context, symbol string) ([]models.RecommendationTrend, error) {
+	return load[models.RecommendationTrend](p, symbol, "recommendations")
+}
+
+func (p *Provider) GetInsiderTransactionsContext(ctx context.Context, symbol string) ([]models.InsiderTransaction, error) {
+	return load[models.InsiderTransaction](p, symbol, "insiders")
+}
+
+// GetCompanyNewsContext returns the fixture news published between from and
+// to (YYYY-MM-DD, inclusive); an empty bound is open.
+func (p *Provider) GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error) {
+	news, err := load[models.CompanyNews](p, symbol, "news")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]models.CompanyNews, 0, len(news))
+	for _, item := range news {
+		published := time.Unix(item.Datetime, 0)
+		if !start.IsZero() && published.Before(start) {
+			continue
+		}
+		if !end.IsZero() && !published.Before(end) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	return filtered, nil
+}
+
+func (p *Provider) GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error) {
+	if !validSymbol(category) {
+		return nil, notFound(category, "news")
+	}
+
+	var news []models.CompanyNews
+	if err := p.readJSON(filepath.Join(p.dir, "news", category+".json"), &news); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	filtered := make([]models.CompanyNews, 0, len(news))
+	for _, item := range news {
+		if item.Id > minID {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+func (p *Provider) GetMarketStatusContext(ctx context.Context, exchange string) (*models.MarketStatus, error) {
+	if !validSymbol(exchange) {
+		return nil, notFound(exchange, "market status")
+	}
+
+	var status models.MarketStatus
+	if err := p.readJSON(filepath.Join(p.dir, "market-status", exchange+".json"), &status); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, notFound(exchange, "market status")
+		}
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetCandlesContext returns the candles in <dir>/<SYMBOL>/candles.json that
+// fall within [from, to]. Fixtures hold a single resolution, so resolution is
+// ignored.
+func (p *Provider) GetCandlesContext(ctx context.Context, symbol, resolution string, from, to time.Time) (*models.Candles, error) {
+	var all models.Candles
+	if err := p.readSymbolJSON(symbol, "candles", &all); err != nil {
+		return nil, err
+	}
+
+	bars, err := all.Bars()
+	if err != nil {
+		return nil, fmt.Errorf("offline: %s candles: %w", symbol, err)
+	}
+
+	candles := &models.Candles{Status: "ok"}
+	for _, bar := range bars {
+		if bar.Time.Before(from) || bar.Time.After(to) {
+			continue
+		}
+		candles.Timestamp = append(candles.Timestamp, bar.Time.Unix())
+		candles.Open = append(candles.Open, bar.Open)
+		candles.High = append(candles.High, bar.High)
+		candles.Low = append(candles.Low, bar.Low)
+		candles.Close = append(candles.Close, bar.Close)
+		candles.Volume = append(candles.Volume, bar.Volume)
+	}
+
+	if len(candles.Timestamp) == 0 {
+		return nil, fmt.Errorf("offline: no candles for %s in range: %w", symbol, provider.ErrNoData)
+	}
+	return candles, nil
+}
+
+func (p *Provider) GetCompanyProfileContext(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	var profile models.CompanyProfile
+	if err := p.readSymbolJSON(symbol, "profile", &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (p *Provider) GetPeersContext(ctx context.Context, symbol string) ([]string, error) {
+	var peers []string
+	if err := p.readSymbolJSON(symbol, "peers", &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+func (p *Provider) GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error) {
+	if !validSymbol(exchange) {
+		return nil, notFound(exchange, "symbols")
+	}
+
+	var symbols []models.StockSymbol
+	if err := p.readJSON(filepath.Join(p.dir, "symbols", exchange+".json"), &symbols); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, notFound(exchange, "symbols")
+		}
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// SearchSymbolsContext matches query against the ticker and description of
+// every listing in <dir>/symbols.
+func (p *Provider) SearchSymbolsContext(ctx context.Context, query string) (*models.SymbolLookup, error) {
+	files, err := filepath.Glob(filepath.Join(p.dir, "symbols", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToUpper(strings.TrimSpace(query))
+	lookup := &models.SymbolLookup{Result: []models.SymbolLookupResult{}}
+
+	for _, file := range files {
+		var symbols []models.StockSymbol
+		if err := p.readJSON(file, &symbols); err != nil {
+			return nil, err
+		}
+
+		for _, symbol := range symbols {
+			if !strings.Contains(strings.ToUpper(symbol.Symbol), query) &&
+				!strings.Contains(strings.ToUpper(symbol.Description), query) {
+				continue
+			}
+			lookup.Result = append(lookup.Result, models.SymbolLookupResult{
+				Description:   symbol.Description,
+				DisplaySymbol: symbol.DisplaySymbol,
+				Symbol:        symbol.Symbol,
+				Type:          symbol.Type,
+			})
+		}
+	}
+
+	lookup.Count = len(lookup.Result)
+	return lookup, nil
+}
+
+func (p *Provider) GetEarningsCalendarContext(ctx context.Context, from, to, symbol string) ([]models.EarningsRelease, error) {
+	return readCalendar(p, "earnings", from, to, func(r models.EarningsRelease) (string, bool) {
+		return r.Date, symbol == "" || r.Symbol == symbol
+	})
+}
+
+func (p *Provider) GetIPOCalendarContext(ctx context.Context, from, to string) ([]models.IPOEvent, error) {
+	return readCalendar(p, "ipo", from, to, func(e models.IPOEvent) (string, bool) {
+		return e.Date, true
+	})
+}
+
+func (p *Provider) GetEconomicCalendarContext(ctx context.Context, from, to string) ([]models.EconomicEvent, error) {
+	return readCalendar(p, "economic", from, to, func(e models.EconomicEvent) (string, bool) {
+		return e.Time, true
+	})
+}
+
+// readCalendar reads <dir>/calendar/<name>.json and keeps the events dated
+// between from and to (YYYY-MM-DD, inclusive) that keep accepts. A missing
+// file is an empty calendar.
+func readCalendar[T any](p *Provider, name, from, to string, keep func(T) (string, bool)) ([]T, error) {
+	start, end, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []T
+	if err := p.readJSON(filepath.Join(p.dir, "calendar", name+".json"), &events); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	filtered := make([]T, 0, len(events))
+	for _, event := range events {
+		date, ok := keep(event)
+		if !ok || len(date) < len(time.DateOnly) {
+			continue
+		}
+
+		day, err := time.Parse(time.DateOnly, date[:len(time.DateOnly)])
+		if err != nil {
+			continue
+		}
+		if !start.IsZero() && day.Before(start) {
+			continue
+		}
+		if !end.IsZero() && !day.Before(end) {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+
+	return filtered, nil
+}
+
+// readSymbolJSON reads <dir>/<symbol>/<name>.json, reporting a missing file
+// or unknown symbol as provider.ErrNotFound.
+func (p *Provider) readSymbolJSON(symbol, name string, out any) error {
+	if !validSymbol(symbol) {
+		return notFound(symbol, name)
+	}
+
+	err := p.readJSON(filepath.Join(p.dir, symbol, name+".json"), out)
+	if errors.Is(err, fs.ErrNotExist) {
+		return notFound(symbol, name)
+	}
+	return err
+}
+
+// load reads <dir>/<symbol>/<name>.json, falling back to <name>.csv. A JSON
+// file may hold a single object or an array. A symbol directory without the
+// file yields no data; an unknown symbol yields provider.ErrNotFound.
+func load[T any](p *Provider, symbol, name string) ([]T, error) {
+	if !validSymbol(symbol) {
+		return nil, notFound(symbol, name)
+	}
+
+	symbolDir := filepath.Join(p.dir, symbol)
+	if _, err := os.Stat(symbolDir); err != nil {
+		return nil, notFound(symbol, name)
+	}
+
+	var raw json.RawMessage
+	err := p.readJSON(filepath.Join(symbolDir, name+".json"), &raw)
+	switch {
+	case err == nil:
+		return decodeJSON[T](raw)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(symbolDir, name+".csv"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []T{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := decodeCSV[T](file)
+	if err != nil {
+		return nil, fmt.Errorf("offline: %s: %w", file.Name(), err)
+	}
+	return rows, nil
+}
+
+func decodeJSON[T any](raw json.RawMessage) ([]T, error) {
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		var rows []T
+		err := json.Unmarshal(raw, &rows)
+		return rows, err
+	}
+
+	var row T
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return nil, err
+	}
+	return []T{row}, nil
+}
+
+func (p *Provider) readJSON(path string, out any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("offline: %s: %w", path, err)
+	}
+	return nil
+}
+
+func dateRange(from, to string) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+
+	if from != "" {
+		if start, err = time.Parse(time.DateOnly, from); err != nil {
+			return start, end, fmt.Errorf("offline: from date %q: %w", from, provider.ErrInvalidRequest)
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse(time.DateOnly, to); err != nil {
+			return start, end, fmt.Errorf("offline: to date %q: %w", to, provider.ErrInvalidRequest)
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return start, end, nil
+}
+
+// validSymbol keeps symbols from escaping the fixture directory.
+func validSymbol(symbol string) bool {
+	return symbol != "" && symbol != "." && symbol != ".." && !strings.ContainsAny(symbol, `/\`)
+}
+
+func notFound(symbol, name string) error {
+	return fmt.Errorf("offline: no %s for %s: %w", name, symbol, provider.ErrNotFound)
+}
@@ -0,0 +1,223 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/provider"
+)
+
+func writeFixture(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProviderReadsJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "AAPL/quote.json", `{"c": 150.25, "pc": 148.5, "t": 1700000000}`)
+	writeFixture(t, dir, "AAPL/recommendations.json", `[{"buy": 10, "period": "2024-01-01", "symbol": "AAPL"}]`)
+
+	p := NewProvider(dir)
+
+	quote, err := p.GetQuoteContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quote.CurrentPrice != 150.25 {
+		t.Errorf("Expected price 150.25, got %f", quote.CurrentPrice)
+	}
+
+	trends, err := p.GetRecommendationsContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(trends) != 1 || trends[0].Buy != 10 {
+		t.Errorf("Expected one trend with 10 buys, got %+v", trends)
+	}
+
+	earnings, err := p.GetEarningsContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(earnings) != 0 {
+		t.Errorf("Expected no earnings, got %d", len(earnings))
+	}
+}
+
+func TestProviderReadsCSV(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "AAPL/insiders.csv", "name,share,change,filingDate,transactionPrice,unknown\n"+
+		"\"COOK, TIMOTHY\",1000,-50,2024-01-02,190.5,x\n"+
+		"JANE DOE,200,,2024-01-03,,\n")
+
+	p := NewProvider(dir)
+
+	transactions, err := p.GetInsiderTransactionsContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(transactions))
+	}
+	if transactions[0].Name != "COOK, TIMOTHY" || transactions[0].Change != -50 || transactions[0].TransactionPrice != 190.5 {
+		t.Errorf("Unexpected first transaction %+v", transactions[0])
+	}
+	if transactions[1].Share != 200 || transactions[1].Change != 0 {
+		t.Errorf("Unexpected second transaction %+v", transactions[1])
+	}
+
+	writeFixture(t, dir, "MSFT/earnings.csv", "actual,quarter\nabc,1\n")
+	if _, err := p.GetEarningsContext(context.Background(), "MSFT"); err == nil {
+		t.Error("Expected error for malformed CSV")
+	}
+}
+
+func TestProviderFiltersNewsByDate(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "AAPL/news.json", `[
+		{"id": 1, "datetime": 1704110400, "headline": "Jan 1"},
+		{"id": 2, "datetime": 1704196800, "headline": "Jan 2"},
+		{"id": 3, "datetime": 1704283200, "headline": "Jan 3"}
+	]`)
+
+	p := NewProvider(dir)
+
+	news, err := p.GetCompanyNewsContext(context.Background(), "AAPL", "2024-01-02", "2024-01-02")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(news) != 1 || news[0].Id != 2 {
+		t.Errorf("Expected only the Jan 2 item, got %+v", news)
+	}
+
+	if _, err := p.GetCompanyNewsContext(context.Background(), "AAPL", "yesterday", ""); !errors.Is(err, provider.ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for invalid date, got %v", err)
+	}
+}
+
+func TestProviderMarketNewsAfterMinID(t *testing.T) {
+	p := NewProvider(filepath.Join("..", "..", "fixtures"))
+
+	news, err := p.GetMarketNewsContext(context.Background(), "general", 7320001)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(news) != 2 {
+		t.Errorf("Expected the 2 stories after the first, got %+v", news)
+	}
+
+	if news, err := p.GetMarketNewsContext(context.Background(), "merger", 0); err != nil || len(news) != 0 {
+		t.Errorf("Expected no merger news, got %v, %v", news, err)
+	}
+}
+
+func TestProviderFiltersCandlesByRange(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "AAPL/candles.json", `{"c":[1,2,3],"h":[1,2,3],"l":[1,2,3],"o":[1,2,3],"s":"ok","t":[1704153600,1704240000,1704326400],"v":[10,20,30]}`)
+
+	p := NewProvider(dir)
+
+	candles, err := p.GetCandlesContext(context.Background(), "AAPL", "D", time.Unix(1704240000, 0), time.Unix(1704326400, 0))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(candles.Close) != 2 || candles.Close[0] != 2 {
+		t.Errorf("Expected the last two candles, got %+v", candles)
+	}
+
+	if _, err := p.GetCandlesContext(context.Background(), "AAPL", "D", time.Unix(0, 0), time.Unix(86400, 0)); !errors.Is(err, provider.ErrNoData) {
+		t.Errorf("Expected ErrNoData, got %v", err)
+	}
+}
+
+func TestProviderFiltersCalendars(t *testing.T) {
+	p := NewProvider(filepath.Join("..", "..", "fixtures"))
+	ctx := context.Background()
+
+	releases, err := p.GetEarningsCalendarContext(ctx, "2024-01-30", "2024-04-30", "MSFT")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(releases) != 2 || releases[1].EpsActual != nil {
+		t.Errorf("Expected two MSFT releases, the last unreported, got %+v", releases)
+	}
+
+	events, err := p.GetEconomicCalendarContext(ctx, "2024-01-11", "2024-01-11")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 1 || events[0].Event != "CPI YoY" {
+		t.Errorf("Expected only the Jan 11 event, got %+v", events)
+	}
+
+	empty := NewProvider(t.TempDir())
+	if ipos, err := empty.GetIPOCalendarContext(ctx, "", ""); err != nil || len(ipos) != 0 {
+		t.Errorf("Expected an empty calendar without fixtures, got %v, %v", ipos, err)
+	}
+}
+
+func TestProviderUnknownSymbol(t *testing.T) {
+	p := NewProvider(t.TempDir())
+
+	for _, symbol := range []string{"NOPE", "../AAPL", ".."} {
+		if _, err := p.GetQuoteContext(context.Background(), symbol); !errors.Is(err, provider.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %q, got %v", symbol, err)
+		}
+		if _, err := p.GetBasicFinancialsContext(context.Background(), symbol); !errors.Is(err, provider.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %q financials, got %v", symbol, err)
+		}
+	}
+}
+
+func TestProviderBundledFixtures(t *testing.T) {
+	p := NewProvider(filepath.Join("..", "..", "fixtures"))
+	ctx := context.Background()
+
+	if _, err := p.GetQuoteContext(ctx, "AAPL"); err != nil {
+		t.Errorf("Expected AAPL quote, got %v", err)
+	}
+	if _, err := p.GetQuoteContext(ctx, "MSFT"); err != nil {
+		t.Errorf("Expected MSFT quote, got %v", err)
+	}
+
+	financials, err := p.GetBasicFinancialsContext(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("Expected AAPL financials, got %v", err)
+	}
+	if len(financials.AnnualSeries("netMargin")) == 0 {
+		t.Error("Expected annual net margin series")
+	}
+
+	if earnings, err := p.GetEarningsContext(ctx, "AAPL"); err != nil || len(earnings) == 0 {
+		t.Errorf("Expected AAPL earnings, got %d, %v", len(earnings), err)
+	}
+	if insiders, err := p.GetInsiderTransactionsContext(ctx, "AAPL"); err != nil || len(insiders) == 0 {
+		t.Errorf("Expected AAPL insiders, got %d, %v", len(insiders), err)
+	}
+	if profile, err := p.GetCompanyProfileContext(ctx, "MSFT"); err != nil || profile.Name != "Microsoft Corp" {
+		t.Errorf("Expected MSFT profile, got %+v, %v", profile, err)
+	}
+	if peers, err := p.GetPeersContext(ctx, "AAPL"); err != nil || len(peers) == 0 {
+		t.Errorf("Expected AAPL peers, got %v, %v", peers, err)
+	}
+	if lookup, err := p.SearchSymbolsContext(ctx, "micro"); err != nil || lookup.Count != 1 || lookup.Result[0].Symbol != "MSFT" {
+		t.Errorf("Expected MSFT for micro, got %+v, %v", lookup, err)
+	}
+	if listing, err := p.GetStockSymbolsContext(ctx, "US"); err != nil || len(listing) == 0 {
+		t.Errorf("Expected US symbols, got %d, %v", len(listing), err)
+	}
+	if _, err := p.GetMarketStatusContext(ctx, "US"); err != nil {
+		t.Errorf("Expected US market status, got %v", err)
+	}
+}
@@ -0,0 +1,368 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+const (
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+type Source struct {
+	Name     string
+	Provider MarketDataProvider
+}
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+type SourceStats struct {
+	Name     string       `json:"name"`
+	State    BreakerState `json:"state"`
+	Served   uint64       `json:"served"`
+	Answered uint64       `json:"answered"`
+	Failures uint64       `json:"failures"`
+}
+
+// breaker opens after threshold consecutive failures and skips its source
+// for cooldown, then lets a single trial request through.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+	mu        sync.Mutex
+}
+
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.trial {
+		return false
+	}
+
+	b.trial = true
+	return true
+}
+
+func (b *breaker) record(now time.Time, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+// release ends a trial without recording an outcome, for requests that say
+// nothing about the source's health.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *breaker) state(now time.Time) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.failures < b.threshold:
+		return BreakerClosed
+	case now.Before(b.openUntil):
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
+type source struct {
+	Source
+	breaker  *breaker
+	served   uint64
+	answered uint64
+	failures uint64
+	mu       sync.Mutex
+}
+
+func (s *source) count(counter *uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*counter++
+}
+
+// Failover tries an ordered list of sources, moving on when a source errors
+// or, for quotes, returns data older than MaxQuoteAge. Each source sits
+// behind a circuit breaker so an unhealthy one is skipped for a while instead
+// of slowing every request down.
+type Failover struct {
+	sources     []*source
+	MaxQuoteAge time.Duration
+}
+
+func NewFailover(sources []Source, failureThreshold int, cooldown time.Duration) *Failover {
+	f := &Failover{}
+	for _, src := range sources {
+		f.sources = append(f.sources, &source{
+			Source:  src,
+			breaker: &breaker{threshold: failureThreshold, cooldown: cooldown},
+		})
+	}
+	return f
+}
+
+func (f *Failover) Stats() []SourceStats {
+	now := time.Now()
+	stats := make([]SourceStats, 0, len(f.sources))
+
+	for _, src := range f.sources {
+		src.mu.Lock()
+		stats = append(stats, SourceStats{
+			Name:     src.Name,
+			State:    src.breaker.state(now),
+			Served:   src.served,
+			Answered: src.answered,
+			Failures: src.failures,
+		})
+		src.mu.Unlock()
+	}
+
+	return stats
+}
+
+// GetQuoteContext returns the first fresh quote. If every source is stale or
+// failing, the freshest stale quote is still preferred over an error. The
+// quote's Source field names the source that served it.
+func (f *Failover) GetQuoteContext(ctx context.Context, symbol string) (*models.StockQuote, error) {
+	var stale *models.StockQuote
+	var firstErr error
+
+	for _, src := range f.sources {
+		quote, err := attempt(ctx, src, func(p MarketDataProvider) (*models.StockQuote, error) {
+			return p.GetQuoteContext(ctx, symbol)
+		})
+		if err != nil {
+			if isFinal(ctx, err, firstErr == nil && stale == nil) {
+				return nil, err
+			}
+			if !isAnswer(err) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		served := *quote
+		served.Source = src.Name
+
+		if f.isStale(&served) {
+			if stale == nil || served.Timestamp > stale.Timestamp {
+				stale = &served
+			}
+			continue
+		}
+
+		return &served, nil
+	}
+
+	if stale != nil {
+		return stale, nil
+	}
+	return nil, unavailable(firstErr)
+}
+
+func (f *Failover) GetBasicFinancialsContext(ctx context.Context, symbol string) (*models.BasicFinancials, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (*models.BasicFinancials, error) {
+		return p.GetBasicFinancialsContext(ctx, symbol)
+	})
+}
+
+func (f *Failover) GetEarningsContext(ctx context.Context, symbol string) ([]models.EarningsSurprise, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.EarningsSurprise, error) {
+		return p.GetEarningsContext(ctx, symbol)
+	})
+}
+
+func (f *Failover) GetRecommendationsContext(ctx context.Context, symbol string) ([]models.RecommendationTrend, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.RecommendationTrend, error) {
+		return p.GetRecommendationsContext(ctx, symbol)
+	})
+}
+
+func (f *Failover) GetInsiderTransactionsContext(ctx context.Context, symbol string) ([]models.InsiderTransaction, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.InsiderTransaction, error) {
+		return p.GetInsiderTransactionsContext(ctx, symbol)
+	})
+}
+
+func (f *Failover) GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.CompanyNews, error) {
+		return p.GetCompanyNewsContext(ctx, symbol, from, to)
+	})
+}
+
+func (f *Failover) GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.CompanyNews, error) {
+		return p.GetMarketNewsContext(ctx, category, minID)
+	})
+}
+
+func (f *Failover) GetMarketStatusContext(ctx context.Context, exchange string) (*models.MarketStatus, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (*models.MarketStatus, error) {
+		return p.GetMarketStatusContext(ctx, exchange)
+	})
+}
+
+func (f *Failover) GetCandlesContext(ctx context.Context, symbol, resolution string, from, to time.Time) (*models.Candles, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (*models.Candles, error) {
+		return p.GetCandlesContext(ctx, symbol, resolution, from, to)
+	})
+}
+
+func (f *Failover) GetCompanyProfileContext(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (*models.CompanyProfile, error) {
+		return p.GetCompanyProfileContext(ctx, symbol)
+	})
+}
+
+func (f *Failover) GetPeersContext(ctx context.Context, symbol string) ([]string, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]string, error) {
+		return p.GetPeersContext(ctx, symbol)
+	})
+}
+
+func (f *Failover) SearchSymbolsContext(ctx context.Context, query string) (*models.SymbolLookup, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (*models.SymbolLookup, error) {
+		return p.SearchSymbolsContext(ctx, query)
+	})
+}
+
+func (f *Failover) GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.StockSymbol, error) {
+		return p.GetStockSymbolsContext(ctx, exchange)
+	})
+}
+
+func (f *Failover) GetEarningsCalendarContext(ctx context.Context, from, to, symbol string) ([]models.EarningsRelease, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.EarningsRelease, error) {
+		return p.GetEarningsCalendarContext(ctx, from, to, symbol)
+	})
+}
+
+func (f *Failover) GetIPOCalendarContext(ctx context.Context, from, to string) ([]models.IPOEvent, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.IPOEvent, error) {
+		return p.GetIPOCalendarContext(ctx, from, to)
+	})
+}
+
+func (f *Failover) GetEconomicCalendarContext(ctx context.Context, from, to string) ([]models.EconomicEvent, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]models.EconomicEvent, error) {
+		return p.GetEconomicCalendarContext(ctx, from, to)
+	})
+}
+
+func (f *Failover) isStale(quote *models.StockQuote) bool {
+	if f.MaxQuoteAge <= 0 {
+		return false
+	}
+	return time.Since(time.Unix(int64(quote.Timestamp), 0)) > f.MaxQuoteAge
+}
+
+// failover returns the first source's value. When every source fails, the
+// error of the first one that failed is returned.
+func failover[T any](ctx context.Context, f *Failover, call func(MarketDataProvider) (T, error)) (T, error) {
+	var zero T
+	var firstErr error
+
+	for _, src := range f.sources {
+		value, err := attempt(ctx, src, call)
+		if err == nil {
+			return value, nil
+		}
+		if isFinal(ctx, err, firstErr == nil) {
+			return zero, err
+		}
+		if !isAnswer(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return zero, unavailable(firstErr)
+}
+
+// attempt calls src unless its breaker is open and records the outcome.
+// Answers like an unknown symbol and cancelled requests say nothing about the
+// source's health: they are neither counted against it nor allowed to close
+// its breaker.
+func attempt[T any](ctx context.Context, src *source, call func(MarketDataProvider) (T, error)) (T, error) {
+	var zero T
+
+	if !src.breaker.allow(time.Now()) {
+		return zero, fmt.Errorf("%s: circuit open: %w", src.Name, ErrUnavailable)
+	}
+
+	value, err := call(src.Provider)
+
+	switch {
+	case err == nil:
+		src.breaker.record(time.Now(), false)
+		src.count(&src.served)
+	case isAnswer(err):
+		src.breaker.release()
+		src.count(&src.answered)
+	case ctx.Err() != nil:
+		src.breaker.release()
+	default:
+		src.breaker.record(time.Now(), true)
+		src.count(&src.failures)
+	}
+
+	return value, err
+}
+
+// isFinal reports whether err ends the search for a value. Not-found and
+// no-data answers are only trusted from the first source that responded
+// while no earlier source failed: a fallback without the data says nothing
+// about what the failed primary would have served.
+func isFinal(ctx context.Context, err error, first bool) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrInvalidRequest) {
+		return true
+	}
+	return first && isAnswer(err)
+}
+
+// isAnswer reports whether err is a valid "nothing there" answer rather than
+// a failure of the source.
+func isAnswer(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrNoData) || errors.Is(err, ErrInvalidRequest)
+}
+
+func unavailable(lastErr error) error {
+	if lastErr == nil {
+		return ErrUnavailable
+	}
+	return lastErr
+}
@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+type stubProvider struct {
+	MarketDataProvider
+	quote *models.StockQuote
+	err   error
+	calls int
+}
+
+func (p *stubProvider) GetQuoteContext(ctx context.Context, symbol string) (*models.StockQuote, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.quote, nil
+}
+
+func (p *stubProvider) GetMarketStatusContext(ctx context.Context, exchange string) (*models.MarketStatus, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.MarketStatus{Exchange: exchange}, nil
+}
+
+func freshQuote(price float64) *models.StockQuote {
+	return &models.StockQuote{CurrentPrice: price, Timestamp: float64(time.Now().Unix())}
+}
+
+func TestFailoverFallsBackOnError(t *testing.T) {
+	primary := &stubProvider{err: errors.New("connection refused")}
+	backup := &stubProvider{quote: freshQuote(150)}
+
+	f := NewFailover([]Source{{Name: "primary", Provider: primary}, {Name: "backup", Provider: backup}}, 3, time.Minute)
+
+	quote, err := f.GetQuoteContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quote.CurrentPrice != 150 {
+		t.Errorf("Expected price 150, got %f", quote.CurrentPrice)
+	}
+	if quote.Source != "backup" {
+		t.Errorf("Expected source backup, got %q", quote.Source)
+	}
+	if backup.quote.Source != "" {
+		t.Error("Expected the source's quote to be left untouched")
+	}
+}
+
+func TestFailoverSkipsStaleQuotes(t *testing.T) {
+	stale := &models.StockQuote{CurrentPrice: 100, Timestamp: float64(time.Now().Add(-time.Hour).Unix())}
+	primary := &stubProvider{quote: stale}
+	backup := &stubProvider{quote: freshQuote(150)}
+
+	f := NewFailover([]Source{{Name: "primary", Provider: primary}, {Name: "backup", Provider: backup}}, 3, time.Minute)
+	f.MaxQuoteAge = time.Minute
+
+	quote, err := f.GetQuoteContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if quote.Source != "backup" {
+		t.Errorf("Expected source backup, got %q", quote.Source)
+	}
+
+	backup.err = errors.New("connection refused")
+
+	quote, err = f.GetQuoteContext(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatalf("Expected stale quote instead of error, got %v", err)
+	}
+	if quote.Source != "primary" || quote.CurrentPrice != 100 {
+		t.Errorf("Expected stale quote from primary, got %+v", quote)
+	}
+}
+
+func TestFailoverDoesNotFallBackOnNotFound(t *testing.T) {
+	primary := &stubProvider{err: ErrNotFound}
+	backup := &stubProvider{quote: freshQuote(150)}
+
+	f := NewFailover([]Source{{Name: "primary", Provider: primary}, {Name: "backup", Provider: backup}}, 1, time.Minute)
+
+	if _, err := f.GetQuoteContext(context.Background(), "NOPE"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if backup.calls != 0 {
+		t.Errorf("Expected backup not to be called, got %d calls", backup.calls)
+	}
+	if state := f.Stats()[0].State; state != BreakerClosed {
+		t.Errorf("Expected breaker closed, got %s", state)
+	}
+}
+
+func TestFailoverKeepsPrimaryErrorOverFallbackNotFound(t *testing.T) {
+	outage := errors.New("finnhub: status 503")
+	primary := &stubProvider{err: outage}
+	backup := &stubProvider{err: ErrNotFound}
+
+	f := NewFailover([]Source{{Name: "primary", Provider: primary}, {Name: "backup", Provider: backup}}, 3, time.Minute)
+
+	if _, err := f.GetQuoteContext(context.Background(), "AAPL"); !errors.Is(err, outage) {
+		t.Errorf("Expected the primary's error for quotes, got %v", err)
+	}
+	if _, err := f.GetMarketStatusContext(context.Background(), "US"); !errors.Is(err, outage) {
+		t.Errorf("Expected the primary's error, got %v", err)
+	}
+
+	stats := f.Stats()
+	if stats[1].Served != 0 || stats[1].Answered != 2 {
+		t.Errorf("Expected backup to count 2 answers and nothing served, got %+v", stats[1])
+	}
+}
+
+func TestFailoverCircuitBreaker(t *testing.T) {
+	primary := &stubProvider{err: errors.New("connection refused")}
+	backup := &stubProvider{}
+
+	f := NewFailover([]Source{{Name: "primary", Provider: primary}, {Name: "backup", Provider: backup}}, 2, time.Minute)
+
+	for i := 0; i < 4; i++ {
+		if _, err := f.GetMarketStatusContext(context.Background(), "US"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if primary.calls != 2 {
+		t.Errorf("Expected primary to be skipped once its breaker opened, got %d calls", primary.calls)
+	}
+
+	stats := f.Stats()
+	if stats[0].State != BreakerOpen {
+		t.Errorf("Expected primary breaker open, got %s", stats[0].State)
+	}
+	if stats[1].Served != 4 {
+		t.Errorf("Expected backup to serve 4 requests, got %d", stats[1].Served)
+	}
+}
+
+func TestFailoverBreakerHalfOpen(t *testing.T) {
+	primary := &stubProvider{err: errors.New("connection refused")}
+
+	f := NewFailover([]Source{{Name: "primary", Provider: primary}}, 1, 50*time.Millisecond)
+
+	if _, err := f.GetQuoteContext(context.Background(), "AAPL"); err == nil {
+		t.Fatal("Expected error")
+	}
+	if _, err := f.GetQuoteContext(context.Background(), "AAPL"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable while open, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	primary.err = nil
+	primary.quote = freshQuote(150)
+
+	if _, err := f.GetQuoteContext(context.Background(), "AAPL"); err != nil {
+		t.Fatalf("Expected trial request to succeed, got %v", err)
+	}
+	if state := f.Stats()[0].State; state != BreakerClosed {
+		t.Errorf("Expected breaker closed after successful trial, got %s", state)
+	}
+}
+
+func TestFailoverHalfOpenTrialNeedsSuccess(t *testing.T) {
+	primary := &stubProvider{err: errors.New("connection refused")}
+
+	f := NewFailover([]Source{{Name: "primary", Provider: primary}}, 1, 50*time.Millisecond)
+	f.GetQuoteContext(context.Background(), "AAPL")
+	time.Sleep(60 * time.Millisecond)
+
+	primary.err = ErrNotFound
+	if _, err := f.GetQuoteContext(context.Background(), "NOPE"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound from the trial, got %v", err)
+	}
+	if state := f.Stats()[0].State; state != BreakerHalfOpen {
+		t.Errorf("Expected breaker to stay half open after a not-found trial, got %s", state)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary.err = context.Canceled
+	f.GetQuoteContext(ctx, "AAPL")
+	if state := f.Stats()[0].State; state != BreakerHalfOpen {
+		t.Errorf("Expected breaker to stay half open after a cancelled trial, got %s", state)
+	}
+
+	primary.err = nil
+	primary.quote = freshQuote(150)
+	if _, err := f.GetQuoteContext(context.Background(), "AAPL"); err != nil {
+		t.Fatalf("Expected a new trial to be let through, got %v", err)
+	}
+	if state := f.Stats()[0].State; state != BreakerClosed {
+		t.Errorf("Expected breaker closed after a successful trial, got %s", state)
+	}
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+// MarketDataProvider is the set of REST market data the server needs.
+// finnhub.Client implements it; caches, fallbacks and test doubles wrap or
+// replace it without touching handler code.
+type MarketDataProvider interface {
+	GetQuoteContext(ctx context.Context, symbol string) (*models.StockQuote, error)
+	GetBasicFinancialsContext(ctx context.Context, symbol string) (*models.BasicFinancials, error)
+	GetEarningsContext(ctx context.Context, symbol string) ([]models.EarningsSurprise, error)
+	GetRecommendationsContext(ctx context.Context, symbol string) ([]models.RecommendationTrend, error)
+	GetInsiderTransactionsContext(ctx context.Context, symbol string) ([]models.InsiderTransaction, error)
+	GetCompanyNewsContext(ctx context.Context, symbol, from, to string) ([]models.CompanyNews, error)
+	GetMarketNewsContext(ctx context.Context, category string, minID int64) ([]models.CompanyNews, error)
+	GetMarketStatusContext(ctx context.Context, exchange string) (*models.MarketStatus, error)
+	GetCandlesContext(ctx context.Context, symbol, resolution string, from, to time.Time) (*models.Candles, error)
+	GetCompanyProfileContext(ctx context.Context, symbol string) (*models.CompanyProfile, error)
+	GetPeersContext(ctx context.Context, symbol string) ([]string, error)
+	SearchSymbolsContext(ctx context.Context, query string) (*models.SymbolLookup, error)
+	GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error)
+	GetEarningsCalendarContext(ctx context.Context, from, to, symbol string) ([]models.EarningsRelease, error)
+	GetIPOCalendarContext(ctx context.Context, from, to string) ([]models.IPOEvent, error)
+	GetEconomicCalendarContext(ctx context.Context, from, to string) ([]models.EconomicEvent, error)
+}
+
+// ErrNotFound is returned by providers for unknown symbols or missing data.
+// It is a valid answer, so it neither trips circuit breakers nor triggers
+// failover.
+var ErrNotFound = errors.New("provider: not found")
+
+// ErrNoData is returned when a known symbol has no data for the requested
+// range. Like ErrNotFound it is a valid answer.
+var ErrNoData = errors.New("provider: no data")
+
+// ErrInvalidRequest is returned for parameters a provider cannot use, such as
+// malformed dates. It is the caller's fault, so it is a valid answer too.
+var ErrInvalidRequest = errors.New("provider: invalid request")
+
+// ErrUnavailable is returned when every data source failed or is being
+// skipped by its circuit breaker.
+var ErrUnavailable = errors.New("provider: no data source available")
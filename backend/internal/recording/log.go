@@ -0,0 +1,263 @@
+package recording
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A log is a gzip stream of entries, each an 8-byte big-endian receive time
+// in Unix nanoseconds, a 4-byte frame length and the raw frame. Every
+// Recorder appends a new gzip member, which readers see as one stream. A
+// member left unterminated by a crash would corrupt everything appended after
+// it, so NewRecorder rewrites such a log before appending.
+
+const (
+	DefaultFlushInterval = time.Second
+	maxFrameSize         = 16 << 20
+)
+
+type Entry struct {
+	Time  time.Time
+	Frame []byte
+}
+
+// Recorder appends frames to a compressed log. Writes are buffered; the
+// buffer is flushed on Close and at most FlushInterval after a frame is
+// recorded, which bounds what a crash can lose.
+type Recorder struct {
+	FlushInterval time.Duration
+	file          *os.File
+	gz            *gzip.Writer
+	lastFlush     time.Time
+	pending       *time.Timer
+	mu            sync.Mutex
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	if err := repair(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		FlushInterval: DefaultFlushInterval,
+		file:          file,
+		gz:            gzip.NewWriter(file),
+		lastFlush:     time.Now(),
+	}, nil
+}
+
+func (r *Recorder) Record(at time.Time, frame []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gz == nil {
+		return errors.New("recorder closed")
+	}
+
+	if err := writeEntry(r.gz, at, frame); err != nil {
+		return err
+	}
+
+	wait := r.FlushInterval - time.Since(r.lastFlush)
+	if wait <= 0 {
+		return r.flushLocked()
+	}
+	if r.pending == nil {
+		r.pending = time.AfterFunc(wait, r.flush)
+	}
+	return nil
+}
+
+// flush writes out frames recorded since the last flush when no later frame
+// has done so.
+func (r *Recorder) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gz == nil {
+		return
+	}
+	if err := r.flushLocked(); err != nil {
+		log.Printf("Stream recording flush error: %v", err)
+	}
+}
+
+// flushLocked must be called with r.mu held.
+func (r *Recorder) flushLocked() error {
+	if r.pending != nil {
+		r.pending.Stop()
+		r.pending = nil
+	}
+	r.lastFlush = time.Now()
+	return r.gz.Flush()
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gz == nil {
+		return nil
+	}
+	if r.pending != nil {
+		r.pending.Stop()
+		r.pending = nil
+	}
+
+	err := r.gz.Close()
+	r.gz = nil
+	return errors.Join(err, r.file.Close())
+}
+
+// repair rewrites the log at path when it does not end cleanly, keeping every
+// entry that can still be read, so that a new member can be appended to it.
+func repair(path string) error {
+	if complete(path) {
+		return nil
+	}
+
+	reader, err := OpenLog(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	recovered := 0
+	var readErr error
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				readErr = err
+			}
+			break
+		}
+		if err := writeEntry(gz, entry.Time, entry.Frame); err != nil {
+			tmp.Close()
+			return err
+		}
+		recovered++
+	}
+
+	if err := errors.Join(gz.Close(), tmp.Close()); err != nil {
+		return err
+	}
+	if readErr != nil {
+		log.Printf("Warning: %s is damaged after %d entries, dropping the rest: %v", path, recovered, readErr)
+	} else {
+		log.Printf("Recovered %d entries of the unfinished recording %s", recovered, path)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// complete reports whether path is missing, empty or a sequence of finished
+// gzip members.
+func complete(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	defer gz.Close()
+
+	_, err = io.Copy(io.Discard, gz)
+	return err == nil
+}
+
+func writeEntry(w io.Writer, at time.Time, frame []byte) error {
+	var header [12]byte
+	binary.BigEndian.PutUint64(header[:8], uint64(at.UnixNano()))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(frame)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+type Reader struct {
+	file *os.File
+	gz   *gzip.Reader
+	buf  *bufio.Reader
+}
+
+func OpenLog(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("recording: %s: %w", path, err)
+	}
+
+	return &Reader{file: file, gz: gz, buf: bufio.NewReader(gz)}, nil
+}
+
+// Next returns the next entry, or io.EOF at the end of the log. A log cut
+// short by a crash ends at its last complete entry.
+func (r *Reader) Next() (Entry, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r.buf, header[:]); err != nil {
+		return Entry{}, endOfLog(err)
+	}
+
+	size := binary.BigEndian.Uint32(header[8:])
+	if size > maxFrameSize {
+		return Entry{}, fmt.Errorf("recording: frame of %d bytes exceeds limit", size)
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r.buf, frame); err != nil {
+		return Entry{}, endOfLog(err)
+	}
+
+	return Entry{
+		Time:  time.Unix(0, int64(binary.BigEndian.Uint64(header[:8]))),
+		Frame: frame,
+	}, nil
+}
+
+func (r *Reader) Close() error {
+	return errors.Join(r.gz.Close(), r.file.Close())
+}
+
+func endOfLog(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return io.EOF
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recordFrames(t *testing.T, path string, start time.Time, frames ...string) {
+	t.Helper()
+
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range frames {
+		if err := recorder.Record(start.Add(time.Duration(i)*time.Second), []byte(frame)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readAll(t *testing.T, path string) []Entry {
+	t.Helper()
+
+	reader, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var entries []Entry
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func TestRecorderAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	start := time.Unix(1700000000, 0)
+
+	recordFrames(t, path, start, `{"type":"ping"}`, `{"type":"trade","data":[]}`)
+	recordFrames(t, path, start.Add(time.Minute), `{"type":"ping"}`)
+
+	entries := readAll(t, path)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries across both sessions, got %d", len(entries))
+	}
+	if string(entries[1].Frame) != `{"type":"trade","data":[]}` {
+		t.Errorf("Expected trade frame, got %s", entries[1].Frame)
+	}
+	if !entries[2].Time.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected %v, got %v", start.Add(time.Minute), entries[2].Time)
+	}
+}
+
+func TestRecorderAppendsAfterUnfinishedSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	start := time.Unix(1700000000, 0)
+
+	// The first session is killed without Close, after its frames were
+	// flushed, so its gzip member has no trailer.
+	crashed, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crashed.FlushInterval = 0
+	crashed.Record(start, []byte("a"))
+	crashed.Record(start.Add(time.Second), []byte("b"))
+
+	recordFrames(t, path, start.Add(time.Minute), "c", "d")
+
+	replayer := NewReplayer(path)
+	replayer.Speed = 0
+
+	out := make(chan []byte, 4)
+	if err := replayer.Start(out); err != nil {
+		t.Fatalf("Expected the whole log to replay, got %v", err)
+	}
+	close(out)
+
+	var frames []string
+	for frame := range out {
+		frames = append(frames, string(frame))
+	}
+	if strings.Join(frames, ",") != "a,b,c,d" {
+		t.Errorf("Expected frames a,b,c,d, got %v", frames)
+	}
+}
+
+func TestRecorderFlushesWhenQuiet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+	recorder.FlushInterval = 20 * time.Millisecond
+
+	recorder.Record(time.Now(), []byte("first"))
+	recorder.Record(time.Now(), []byte("last"))
+	time.Sleep(100 * time.Millisecond)
+
+	entries := readAll(t, path)
+	if len(entries) != 2 || string(entries[1].Frame) != "last" {
+		t.Errorf("Expected both frames to be flushed without another Record, got %d entries", len(entries))
+	}
+}
+
+func TestReaderStopsAtTruncatedEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.FlushInterval = 0
+	recorder.Record(time.Now(), []byte("first"))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Record(time.Now(), []byte("second"))
+
+	// Simulate a crash: the gzip trailer is never written and the last
+	// entry is cut in half.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cut := info.Size() + (int64(len(data))-info.Size())/2
+	if err := os.WriteFile(path, data[:cut], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readAll(t, path)
+	if len(entries) != 1 || string(entries[0].Frame) != "first" {
+		t.Errorf("Expected only the first entry, got %d entries", len(entries))
+	}
+}
+
+func TestReplayerFastForward(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	recordFrames(t, path, time.Now(), "a", "b", "c")
+
+	replayer := NewReplayer(path)
+	replayer.Speed = 0
+
+	out := make(chan []byte, 3)
+	if err := replayer.Start(out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	close(out)
+	var got string
+	for frame := range out {
+		got += string(frame)
+	}
+	if got != "abc" {
+		t.Errorf("Expected frames abc, got %s", got)
+	}
+}
+
+func TestReplayerAcceleratedSpeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	// Frames recorded one second apart.
+	recordFrames(t, path, time.Now(), "a", "b", "c")
+
+	replayer := NewReplayer(path)
+	replayer.Speed = 20
+
+	out := make(chan []byte, 3)
+	start := time.Now()
+	if err := replayer.Start(out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected about 100ms at 20x, took %v", elapsed)
+	}
+}
+
+func TestReplayerStepwise(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	recordFrames(t, path, time.Now(), "a", "b")
+
+	replayer := NewReplayer(path)
+	replayer.Stepwise = true
+	defer replayer.Stop()
+
+	out := make(chan []byte, 2)
+	finished := make(chan error)
+	go func() { finished <- replayer.Start(out) }()
+
+	select {
+	case frame := <-out:
+		t.Fatalf("Expected no frame before Step, got %s", frame)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	replayer.Step()
+	if frame := <-out; string(frame) != "a" {
+		t.Errorf("Expected frame a, got %s", frame)
+	}
+
+	replayer.Step()
+	if frame := <-out; string(frame) != "b" {
+		t.Errorf("Expected frame b, got %s", frame)
+	}
+
+	if err := <-finished; err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if replayer.Step() {
+		t.Error("Expected Step to report the end of the log")
+	}
+}
@@ -0,0 +1,115 @@
+package recording
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Replayer reads a recorded log and pushes its frames into a channel with the
+// same contract as finnhub.StreamClient.Start. Speed scales the recorded gaps
+// between frames: 1 is real time, 10 is ten times faster and 0 sends frames
+// back to back. In stepwise mode each frame waits for a call to Step.
+type Replayer struct {
+	Path     string
+	Speed    float64
+	Stepwise bool
+	step     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewReplayer(path string) *Replayer {
+	return &Replayer{
+		Path:  path,
+		Speed: 1,
+		step:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start blocks until the log is exhausted or Stop is called. Either way the
+// replayer is stopped when it returns, so pending Steps return false.
+func (r *Replayer) Start(outputChan chan<- []byte) error {
+	defer r.Stop()
+
+	reader, err := OpenLog(r.Path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var previous time.Time
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			log.Printf("Replay of %s finished", r.Path)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !r.wait(previous, entry.Time) {
+			return nil
+		}
+		previous = entry.Time
+
+		select {
+		case outputChan <- entry.Frame:
+		case <-r.done:
+			return nil
+		}
+	}
+}
+
+// Step releases the next frame in stepwise mode. It blocks until the replayer
+// is ready for it and returns false once replay has stopped.
+func (r *Replayer) Step() bool {
+	select {
+	case r.step <- struct{}{}:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+func (r *Replayer) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+// wait holds the next frame back according to the replay mode and reports
+// whether replay should continue.
+func (r *Replayer) wait(previous, next time.Time) bool {
+	if r.Stepwise {
+		select {
+		case <-r.step:
+			return true
+		case <-r.done:
+			return false
+		}
+	}
+
+	if previous.IsZero() || r.Speed <= 0 {
+		return true
+	}
+
+	delay := time.Duration(float64(next.Sub(previous)) / r.Speed)
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.done:
+		return false
+	}
+}
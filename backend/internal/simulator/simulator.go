@@ -0,0 +1,222 @@
+package simulator
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/finnhub"
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+const (
+	DefaultTickInterval = 500 * time.Millisecond
+	DefaultVolatility   = 0.001
+	DefaultPrice        = 100.0
+)
+
+type Model int
+
+const (
+	// GBM moves the price by a normally distributed log return, so steps
+	// scale with the price and it never goes negative.
+	GBM Model = iota
+	// RandomWalk moves the price by a normally distributed amount relative to
+	// the symbol's starting price.
+	RandomWalk
+)
+
+func (m Model) String() string {
+	switch m {
+	case GBM:
+		return "gbm"
+	case RandomWalk:
+		return "random_walk"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseModel(s string) (Model, error) {
+	switch s {
+	case "gbm":
+		return GBM, nil
+	case "random_walk":
+		return RandomWalk, nil
+	default:
+		return GBM, fmt.Errorf("unknown simulator model %q", s)
+	}
+}
+
+type series struct {
+	start float64
+	price float64
+}
+
+// Simulator generates synthetic trades for every subscribed symbol. It has
+// the same contract as finnhub.StreamClient: Start pushes trade frames into a
+// channel until Stop, and Subscribe/Unsubscribe make it usable as the hub's
+// upstream. The same seed and subscriptions produce the same prices.
+type Simulator struct {
+	Symbols      []string
+	Model        Model
+	Volatility   float64
+	Drift        float64
+	TickInterval time.Duration
+	// InitialPrice returns the starting price for a newly subscribed symbol.
+	// It is called from the tick loop, so it may block on a quote lookup.
+	// Nil or non-positive results fall back to DefaultPrice.
+	InitialPrice func(symbol string) float64
+	rng          *rand.Rand
+	subscribed   map[string]*series
+	done         chan struct{}
+	stopOnce     sync.Once
+	mu           sync.Mutex
+}
+
+func New(symbols []string, seed uint64) *Simulator {
+	return &Simulator{
+		Symbols:      symbols,
+		Model:        GBM,
+		Volatility:   DefaultVolatility,
+		TickInterval: DefaultTickInterval,
+		rng:          rand.New(rand.NewPCG(seed, seed)),
+		subscribed:   make(map[string]*series),
+		done:         make(chan struct{}),
+	}
+}
+
+func (s *Simulator) Subscribe(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribed[symbol]; !ok {
+		s.subscribed[symbol] = nil
+	}
+}
+
+func (s *Simulator) Unsubscribe(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribed, symbol)
+}
+
+// Start emits one trade frame per tick until Stop is called. It blocks.
+func (s *Simulator) Start(outputChan chan<- []byte) {
+	for _, symbol := range s.Symbols {
+		s.Subscribe(symbol)
+	}
+
+	ticker := time.NewTicker(s.TickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			trades := s.tick(now)
+			if len(trades) == 0 {
+				continue
+			}
+
+			message, err := finnhub.EncodeTrades(trades)
+			if err != nil {
+				log.Printf("Simulator encode error: %v", err)
+				continue
+			}
+
+			select {
+			case outputChan <- message:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+func (s *Simulator) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// tick advances every subscribed symbol by one step, in symbol order so a
+// seeded run is reproducible.
+func (s *Simulator) tick(now time.Time) []models.Trade {
+	s.mu.Lock()
+	symbols := make([]string, 0, len(s.subscribed))
+	for symbol, state := range s.subscribed {
+		if state == nil {
+			symbols = append(symbols, symbol)
+		}
+	}
+	s.mu.Unlock()
+
+	// Look up starting prices without holding the lock.
+	starts := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		starts[symbol] = s.initialPrice(symbol)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbols = symbols[:0]
+	for symbol, state := range s.subscribed {
+		if state == nil {
+			start, ok := starts[symbol]
+			if !ok {
+				// Subscribed after the lookup; it starts on the next tick.
+				continue
+			}
+			state = &series{start: start, price: start}
+			s.subscribed[symbol] = state
+		}
+		symbols = append(symbols, symbol)
+	}
+	slices.Sort(symbols)
+
+	trades := make([]models.Trade, 0, len(symbols))
+	for _, symbol := range symbols {
+		state := s.subscribed[symbol]
+		state.price = s.step(state)
+
+		trades = append(trades, models.Trade{
+			Price:     math.Round(state.price*100) / 100,
+			Symbol:    symbol,
+			Timestamp: now.UnixMilli(),
+			Volume:    float64(1 + s.rng.IntN(100)),
+		})
+	}
+
+	return trades
+}
+
+// step must be called with s.mu held.
+func (s *Simulator) step(state *series) float64 {
+	z := s.rng.NormFloat64()
+
+	switch s.Model {
+	case RandomWalk:
+		price := state.price + s.Drift*state.start + s.Volatility*state.start*z
+		// Keep the walk away from zero and negative prices.
+		return max(price, 0.01)
+	default:
+		return state.price * math.Exp(s.Drift-s.Volatility*s.Volatility/2+s.Volatility*z)
+	}
+}
+
+func (s *Simulator) initialPrice(symbol string) float64 {
+	if s.InitialPrice != nil {
+		if price := s.InitialPrice(symbol); price > 0 {
+			return price
+		}
+	}
+	return DefaultPrice
+}
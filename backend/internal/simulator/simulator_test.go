@@ -0,0 +1,104 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/finnhub"
+)
+
+func TestSimulatorIsReproducible(t *testing.T) {
+	run := func() []float64 {
+		sim := New([]string{"AAPL", "MSFT"}, 42)
+		sim.Subscribe("AAPL")
+		sim.Subscribe("MSFT")
+
+		var prices []float64
+		now := time.Now()
+		for i := 0; i < 50; i++ {
+			for _, trade := range sim.tick(now) {
+				prices = append(prices, trade.Price)
+			}
+		}
+		return prices
+	}
+
+	first, second := run(), run()
+	if len(first) != 100 {
+		t.Fatalf("Expected 100 trades, got %d", len(first))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected identical runs, trade %d differs: %f vs %f", i, first[i], second[i])
+		}
+	}
+}
+
+func TestSimulatorModels(t *testing.T) {
+	for _, model := range []Model{GBM, RandomWalk} {
+		sim := New(nil, 1)
+		sim.Model = model
+		sim.Volatility = 0.05
+		sim.InitialPrice = func(symbol string) float64 { return 250 }
+		sim.Subscribe("AAPL")
+
+		trades := sim.tick(time.Now())
+		if len(trades) != 1 || trades[0].Symbol != "AAPL" {
+			t.Fatalf("Expected one AAPL trade, got %+v", trades)
+		}
+		if trades[0].Price < 200 || trades[0].Price > 300 {
+			t.Errorf("Expected %s to start near 250, got %f", model, trades[0].Price)
+		}
+
+		for i := 0; i < 1000; i++ {
+			for _, trade := range sim.tick(time.Now()) {
+				if trade.Price <= 0 || trade.Volume <= 0 {
+					t.Fatalf("Expected positive price and volume from %s, got %+v", model, trade)
+				}
+			}
+		}
+	}
+}
+
+func TestSimulatorUnsubscribe(t *testing.T) {
+	sim := New(nil, 1)
+	sim.Subscribe("AAPL")
+	sim.Subscribe("MSFT")
+	sim.Unsubscribe("AAPL")
+
+	trades := sim.tick(time.Now())
+	if len(trades) != 1 || trades[0].Symbol != "MSFT" {
+		t.Errorf("Expected only MSFT trades, got %+v", trades)
+	}
+}
+
+func TestSimulatorStart(t *testing.T) {
+	sim := New([]string{"AAPL"}, 1)
+	sim.TickInterval = 10 * time.Millisecond
+	defer sim.Stop()
+
+	out := make(chan []byte)
+	go sim.Start(out)
+
+	select {
+	case raw := <-out:
+		frame, err := finnhub.DecodeFrame(raw)
+		if err != nil {
+			t.Fatalf("Expected a valid frame, got %v", err)
+		}
+		if frame.Type != finnhub.FrameTrade || len(finnhub.ValidTrades(frame.Trades)) != 1 {
+			t.Errorf("Expected one valid trade, got %+v", frame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a simulated trade")
+	}
+}
+
+func TestParseModel(t *testing.T) {
+	if model, err := ParseModel("random_walk"); err != nil || model != RandomWalk {
+		t.Errorf("Expected RandomWalk, got %v, %v", model, err)
+	}
+	if _, err := ParseModel("brownian"); err == nil {
+		t.Error("Expected error for unknown model")
+	}
+}
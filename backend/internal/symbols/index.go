@@ -0,0 +1,255 @@
+package symbols
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+const (
+	DefaultRefreshInterval = 24 * time.Hour
+
+	checkInterval  = time.Hour
+	refreshTimeout = time.Minute
+)
+
+type Source interface {
+	GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error)
+}
+
+// listing is one exchange's symbols as persisted to <dir>/<EXCHANGE>.json.
+type listing struct {
+	Exchange string               `json:"exchange"`
+	Updated  time.Time            `json:"updated"`
+	Symbols  []models.StockSymbol `json:"symbols"`
+
+	entries []entry
+}
+
+type Stats struct {
+	Exchange string    `json:"exchange"`
+	Symbols  int       `json:"symbols"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Index keeps the symbol listings of a few exchanges in memory for search.
+// Listings are persisted to Dir so a restart does not refetch them, and are
+// refreshed once they are older than RefreshInterval.
+type Index struct {
+	Dir             string
+	Exchanges       []string
+	RefreshInterval time.Duration
+
+	// Context wraps the context of each background refresh, e.g. to lower
+	// its rate limiter priority.
+	Context func(context.Context) context.Context
+
+	source   Source
+	listings map[string]*listing
+	mu       sync.RWMutex
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewIndex(source Source, dir string, exchanges []string) *Index {
+	return &Index{
+		Dir:             dir,
+		Exchanges:       exchanges,
+		RefreshInterval: DefaultRefreshInterval,
+		source:          source,
+		listings:        make(map[string]*listing),
+		done:            make(chan struct{}),
+	}
+}
+
+// Load reads the persisted listings. Missing files are not an error; those
+// exchanges are fetched on the next refresh.
+func (idx *Index) Load() error {
+	var errs []error
+
+	for _, exchange := range idx.Exchanges {
+		data, err := os.ReadFile(idx.path(exchange))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var l listing
+		if err := json.Unmarshal(data, &l); err != nil {
+			errs = append(errs, fmt.Errorf("symbols: %s: %w", idx.path(exchange), err))
+			continue
+		}
+		idx.store(exchange, &l)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Refresh fetches an exchange's listing and persists it.
+func (idx *Index) Refresh(ctx context.Context, exchange string) error {
+	symbols, err := idx.source.GetStockSymbolsContext(ctx, exchange)
+	if err != nil {
+		return fmt.Errorf("symbols: refresh %s: %w", exchange, err)
+	}
+
+	l := &listing{Exchange: exchange, Updated: time.Now().UTC(), Symbols: symbols}
+	idx.store(exchange, l)
+
+	return idx.save(l)
+}
+
+// Start refreshes stale listings right away and then keeps checking for
+// stale ones until Stop is called.
+func (idx *Index) Start() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		idx.refreshStale()
+
+		select {
+		case <-idx.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (idx *Index) Stop() {
+	idx.stopOnce.Do(func() {
+		close(idx.done)
+	})
+}
+
+func (idx *Index) Stats() []Stats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	stats := make([]Stats, 0, len(idx.Exchanges))
+	for _, exchange := range idx.Exchanges {
+		s := Stats{Exchange: exchange}
+		if l := idx.listings[exchange]; l != nil {
+			s.Symbols = len(l.Symbols)
+			s.Updated = l.Updated
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// Len returns the number of indexed symbols across all exchanges.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := 0
+	for _, l := range idx.listings {
+		n += len(l.entries)
+	}
+	return n
+}
+
+// Search returns up to limit matches for query across every indexed exchange.
+func (idx *Index) Search(query string, limit int) []Match {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	entries := make([][]entry, 0, len(idx.Exchanges))
+	for _, exchange := range idx.Exchanges {
+		if l := idx.listings[exchange]; l != nil {
+			entries = append(entries, l.entries)
+		}
+	}
+
+	return search(entries, query, limit)
+}
+
+func (idx *Index) refreshStale() {
+	for _, exchange := range idx.Exchanges {
+		if !idx.stale(exchange, time.Now()) {
+			continue
+		}
+
+		ctx := context.Background()
+		if idx.Context != nil {
+			ctx = idx.Context(ctx)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+		err := idx.Refresh(ctx, exchange)
+		cancel()
+
+		if err != nil {
+			log.Printf("Symbol index error: %v", err)
+			continue
+		}
+		log.Printf("Refreshed %s symbol index", exchange)
+	}
+}
+
+func (idx *Index) stale(exchange string, now time.Time) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	l := idx.listings[exchange]
+	return l == nil || now.Sub(l.Updated) >= idx.RefreshInterval
+}
+
+func (idx *Index) store(exchange string, l *listing) {
+	l.entries = make([]entry, len(l.Symbols))
+	for i, symbol := range l.Symbols {
+		l.entries[i] = newEntry(symbol, exchange)
+	}
+
+	idx.mu.Lock()
+	idx.listings[exchange] = l
+	idx.mu.Unlock()
+}
+
+// save writes through a temporary file so a crash never leaves a truncated
+// listing behind.
+func (idx *Index) save(l *listing) error {
+	if idx.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(idx.Dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(idx.Dir, l.Exchange+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), idx.path(l.Exchange))
+}
+
+func (idx *Index) path(exchange string) string {
+	return filepath.Join(idx.Dir, exchange+".json")
+}
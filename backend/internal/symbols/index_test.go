@@ -0,0 +1,104 @@
+package symbols
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+type fakeSource struct {
+	listings map[string][]models.StockSymbol
+	calls    int
+	ctx      context.Context
+}
+
+func (f *fakeSource) GetStockSymbolsContext(ctx context.Context, exchange string) ([]models.StockSymbol, error) {
+	f.calls++
+	f.ctx = ctx
+	symbols, ok := f.listings[exchange]
+	if !ok {
+		return nil, errors.New("unknown exchange")
+	}
+	return symbols, nil
+}
+
+func TestIndexRefreshPersistsAndLoads(t *testing.T) {
+	dir := t.TempDir()
+	source := &fakeSource{listings: map[string][]models.StockSymbol{"US": listingUS}}
+
+	idx := NewIndex(source, dir, []string{"US"})
+	if err := idx.Refresh(context.Background(), "US"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := idx.Search("MSFT", 1); len(got) != 1 || got[0].Exchange != "US" {
+		t.Errorf("Expected MSFT from US, got %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "US.json")); err != nil {
+		t.Fatalf("Expected persisted listing, got %v", err)
+	}
+
+	restarted := NewIndex(source, dir, []string{"US"})
+	if err := restarted.Load(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if restarted.Len() != len(listingUS) {
+		t.Errorf("Expected %d symbols after load, got %d", len(listingUS), restarted.Len())
+	}
+	if restarted.stale("US", time.Now()) {
+		t.Error("Expected a fresh listing not to be stale")
+	}
+	if !restarted.stale("US", time.Now().Add(DefaultRefreshInterval)) {
+		t.Error("Expected the listing to go stale after a day")
+	}
+
+	restarted.refreshStale()
+	if source.calls != 1 {
+		t.Errorf("Expected the loaded listing not to be refetched, got %d calls", source.calls)
+	}
+}
+
+func TestIndexLoadMissingAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "L.json"), []byte("{"), 0o644)
+
+	idx := NewIndex(&fakeSource{}, dir, []string{"US", "L"})
+	if err := idx.Load(); err == nil {
+		t.Error("Expected error for corrupt listing")
+	}
+	if idx.Len() != 0 {
+		t.Errorf("Expected an empty index, got %d", idx.Len())
+	}
+}
+
+func TestIndexRefreshErrorKeepsOldListing(t *testing.T) {
+	source := &fakeSource{listings: map[string][]models.StockSymbol{"US": listingUS}}
+	idx := NewIndex(source, "", []string{"US"})
+	idx.Refresh(context.Background(), "US")
+
+	source.listings = nil
+	if err := idx.Refresh(context.Background(), "US"); err == nil {
+		t.Error("Expected refresh error")
+	}
+	if idx.Len() != len(listingUS) {
+		t.Errorf("Expected the old listing to stay, got %d symbols", idx.Len())
+	}
+}
+
+func TestIndexRefreshUsesContext(t *testing.T) {
+	type key struct{}
+	source := &fakeSource{listings: map[string][]models.StockSymbol{"US": listingUS}}
+	idx := NewIndex(source, "", []string{"US"})
+	idx.Context = func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, key{}, "background")
+	}
+
+	idx.refreshStale()
+	if source.calls != 1 || source.ctx.Value(key{}) != "background" {
+		t.Errorf("Expected the refresh to use the wrapped context, got %d calls", source.calls)
+	}
+}
@@ -0,0 +1,247 @@
+package symbols
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+// primaryMICs are the venues whose listings are preferred over OTC and
+// foreign lines of the same company.
+var primaryMICs = map[string]bool{
+	"XNAS": true,
+	"XNYS": true,
+	"XASE": true,
+	"ARCX": true,
+	"BATS": true,
+}
+
+const (
+	scoreExactTicker  = 1000
+	scoreTickerPrefix = 800
+	scoreNamePrefix   = 600
+	scoreWordPrefix   = 500
+	scoreNameContains = 300
+	scoreFuzzy        = 200
+
+	bonusCommonStock = 50
+	bonusPrimaryMIC  = 30
+	penaltyDotted    = 20
+)
+
+type Match struct {
+	Symbol        string `json:"symbol"`
+	DisplaySymbol string `json:"displaySymbol"`
+	Description   string `json:"description"`
+	Type          string `json:"type"`
+	Mic           string `json:"mic,omitempty"`
+	Exchange      string `json:"exchange,omitempty"`
+	Score         int    `json:"score"`
+}
+
+// entry is a symbol prepared for matching.
+type entry struct {
+	symbol   models.StockSymbol
+	exchange string
+	ticker   string
+	name     string
+	words    []string
+}
+
+func newEntry(symbol models.StockSymbol, exchange string) entry {
+	name := strings.ToUpper(symbol.Description)
+	return entry{
+		symbol:   symbol,
+		exchange: exchange,
+		ticker:   strings.ToUpper(symbol.Symbol),
+		name:     name,
+		words:    strings.FieldsFunc(name, func(r rune) bool { return r == ' ' || r == '-' || r == ',' || r == '.' }),
+	}
+}
+
+// Rank orders symbols that did not come from the index, such as Finnhub's
+// own search results, the same way the index does. Symbols that do not match
+// query locally are kept at the bottom, since Finnhub also matches on
+// identifiers like ISIN and CUSIP.
+func Rank(query string, symbols []models.StockSymbol, limit int) []Match {
+	entries := make([]entry, len(symbols))
+	for i, symbol := range symbols {
+		entries[i] = newEntry(symbol, "")
+	}
+
+	query = strings.ToUpper(strings.TrimSpace(query))
+	return top(collect([][]entry{entries}, query, func(e entry, query string) int {
+		return max(directScore(e, query), fuzzyScore(e, query), 1)
+	}), limit)
+}
+
+// search ranks prefix and substring matches on the ticker and name. Fuzzy
+// matches, which tolerate a typo or two, are only considered when nothing
+// matches directly. The same ticker listed on several exchanges is returned
+// once. entries holds one slice per exchange, so listings are not copied.
+func search(entries [][]entry, query string, limit int) []Match {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if query == "" {
+		return []Match{}
+	}
+
+	matches := collect(entries, query, directScore)
+	if len(matches) == 0 {
+		matches = collect(entries, query, fuzzyScore)
+	}
+
+	return top(matches, limit)
+}
+
+// top sorts matches best first and keeps the first limit distinct tickers.
+func top(matches []Match, limit int) []Match {
+	if limit <= 0 {
+		return []Match{}
+	}
+
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(len(a.Symbol), len(b.Symbol)),
+			cmp.Compare(a.Symbol, b.Symbol),
+		)
+	})
+
+	results := make([]Match, 0, min(limit, len(matches)))
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		if seen[match.Symbol] {
+			continue
+		}
+		seen[match.Symbol] = true
+
+		results = append(results, match)
+		if len(results) == limit {
+			break
+		}
+	}
+
+	return results
+}
+
+func collect(entries [][]entry, query string, score func(entry, string) int) []Match {
+	var matches []Match
+	for _, group := range entries {
+		for _, e := range group {
+			s := score(e, query)
+			if s == 0 {
+				continue
+			}
+
+			matches = append(matches, Match{
+				Symbol:        e.symbol.Symbol,
+				DisplaySymbol: e.symbol.DisplaySymbol,
+				Description:   e.symbol.Description,
+				Type:          e.symbol.Type,
+				Mic:           e.symbol.Mic,
+				Exchange:      e.exchange,
+				Score:         s + listingBonus(e.symbol),
+			})
+		}
+	}
+	return matches
+}
+
+func directScore(e entry, query string) int {
+	switch {
+	case e.ticker == query:
+		return scoreExactTicker
+	case strings.HasPrefix(e.ticker, query):
+		return scoreTickerPrefix - 10*(len(e.ticker)-len(query))
+	case strings.HasPrefix(e.name, query):
+		return scoreNamePrefix
+	case slices.ContainsFunc(e.words, func(w string) bool { return strings.HasPrefix(w, query) }):
+		return scoreWordPrefix
+	case len(query) >= 3 && strings.Contains(e.name, query):
+		return scoreNameContains
+	default:
+		return 0
+	}
+}
+
+// fuzzyScore allows one edit for short queries and two for longer ones,
+// against the ticker and the start of each name word. Strings whose length
+// alone differs by more than that are skipped without computing a distance.
+func fuzzyScore(e entry, query string) int {
+	if len(query) < 2 {
+		return 0
+	}
+	maxEdits := 1
+	if len(query) >= 6 {
+		maxEdits = 2
+	}
+
+	best := maxEdits + 1
+	if abs(len(e.ticker)-len(query)) <= maxEdits {
+		best = min(best, distance(query, e.ticker))
+	}
+	for _, word := range e.words {
+		if len(word) > len(query) {
+			word = word[:len(query)]
+		}
+		if len(query)-len(word) <= maxEdits {
+			best = min(best, distance(query, word))
+		}
+	}
+
+	if best > maxEdits {
+		return 0
+	}
+	return scoreFuzzy - 50*best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func listingBonus(symbol models.StockSymbol) int {
+	bonus := 0
+	if symbol.Type == "Common Stock" {
+		bonus += bonusCommonStock
+	}
+	if primaryMICs[symbol.Mic] {
+		bonus += bonusPrimaryMIC
+	}
+	if strings.Contains(symbol.Symbol, ".") {
+		bonus -= penaltyDotted
+	}
+	return bonus
+}
+
+// distance is the Damerau-Levenshtein (optimal string alignment) distance,
+// so a swapped pair of letters counts as a single typo.
+func distance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,109 @@
+package symbols
+
+import (
+	"testing"
+
+	"github.com/rinz5/co-finance/backend/internal/models"
+)
+
+var listingUS = []models.StockSymbol{
+	{Description: "APPLE INC", DisplaySymbol: "AAPL", Mic: "XNAS", Symbol: "AAPL", Type: "Common Stock"},
+	{Description: "APPLE HOSPITALITY REIT INC", DisplaySymbol: "APLE", Mic: "XNYS", Symbol: "APLE", Type: "REIT"},
+	{Description: "APPLIED MATERIALS INC", DisplaySymbol: "AMAT", Mic: "XNAS", Symbol: "AMAT", Type: "Common Stock"},
+	{Description: "MICROSOFT CORP", DisplaySymbol: "MSFT", Mic: "XNAS", Symbol: "MSFT", Type: "Common Stock"},
+	{Description: "MICROSOFT CORP", DisplaySymbol: "MSFTX", Mic: "OOTC", Symbol: "MSFTX", Type: "Common Stock"},
+	{Description: "BERKSHIRE HATHAWAY INC-CL B", DisplaySymbol: "BRK.B", Mic: "XNYS", Symbol: "BRK.B", Type: "Common Stock"},
+	{Description: "ALPHABET INC-CL A", DisplaySymbol: "GOOGL", Mic: "XNAS", Symbol: "GOOGL", Type: "Common Stock"},
+}
+
+func symbolsOf(matches []Match) []string {
+	out := make([]string, len(matches))
+	for i, match := range matches {
+		out[i] = match.Symbol
+	}
+	return out
+}
+
+func TestRankingFavoursPrimaryListings(t *testing.T) {
+	entries := make([]entry, len(listingUS))
+	for i, symbol := range listingUS {
+		entries[i] = newEntry(symbol, "US")
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"aapl", []string{"AAPL"}},
+		{"MSFT", []string{"MSFT", "MSFTX"}},
+		{"microsoft", []string{"MSFT", "MSFTX"}},
+		{"apple", []string{"AAPL", "APLE"}},
+		{"hathaway", []string{"BRK.B"}},
+		{"APPL", []string{"AAPL"}},
+		{"alphabte", []string{"GOOGL"}},
+		{"zzzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		got := symbolsOf(search([][]entry{entries}, tt.query, 10))
+		if len(got) < len(tt.want) {
+			t.Errorf("Expected %v for %q, got %v", tt.want, tt.query, got)
+			continue
+		}
+		for i, symbol := range tt.want {
+			if got[i] != symbol {
+				t.Errorf("Expected %v first for %q, got %v", tt.want, tt.query, got)
+				break
+			}
+		}
+		if len(tt.want) == 0 && len(got) != 0 {
+			t.Errorf("Expected no matches for %q, got %v", tt.query, got)
+		}
+	}
+}
+
+func TestSearchLimitAndDedupe(t *testing.T) {
+	entries := [][]entry{
+		{newEntry(listingUS[0], "US"), newEntry(listingUS[1], "US"), newEntry(listingUS[2], "US")},
+		{newEntry(listingUS[0], "NASDAQ")},
+	}
+
+	if got := symbolsOf(search(entries, "AP", 10)); len(got) != 3 || got[0] != "APLE" {
+		t.Errorf("Expected the APLE ticker match first among 3, got %v", got)
+	}
+	if got := search(entries, "AP", 1); len(got) != 1 {
+		t.Errorf("Expected 1 match, got %d", len(got))
+	}
+}
+
+func TestRankKeepsUnmatchedResults(t *testing.T) {
+	found := []models.StockSymbol{
+		{Description: "APPLE INC", Symbol: "AAPL", Type: "Common Stock"},
+		{Description: "APPLE INC", Symbol: "AAPL.SW", Type: "Common Stock"},
+	}
+
+	got := symbolsOf(Rank("US0378331005", found, 10))
+	if len(got) != 2 || got[0] != "AAPL" {
+		t.Errorf("Expected both results with AAPL first, got %v", got)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"AAPL", "AAPL", 0},
+		{"APPL", "AAPL", 1},
+		{"MSTF", "MSFT", 1},
+		{"ALPHABTE", "ALPHABET", 1},
+		{"", "ABC", 3},
+		{"KITTEN", "SITTING", 3},
+	}
+
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Expected distance(%q, %q) = %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest queued message to make room.
+	DropOldest OverflowPolicy = iota
+	// CoalesceLatest replaces a queued message for the same symbol with the
+	// newer one, falling back to DropOldest when there is none.
+	CoalesceLatest
+	// Disconnect evicts the client.
+	Disconnect
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop_oldest"
+	case CoalesceLatest:
+		return "coalesce"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "drop_oldest":
+		return DropOldest, nil
+	case "coalesce":
+		return CoalesceLatest, nil
+	case "disconnect":
+		return Disconnect, nil
+	default:
+		return DropOldest, fmt.Errorf("unknown overflow policy %q", s)
+	}
+}
+
+type ClientConfig struct {
+	QueueSize  int
+	WriteWait  time.Duration
+	PongWait   time.Duration
+	PingPeriod time.Duration
+	Policy     OverflowPolicy
+}
+
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		QueueSize:  256,
+		WriteWait:  10 * time.Second,
+		PongWait:   60 * time.Second,
+		PingPeriod: 54 * time.Second,
+		Policy:     DropOldest,
+	}
+}
+
+type outbound struct {
+	key  string
+	data []byte
+}
+
+// Client wraps a browser connection with a bounded outbound queue drained by
+// its own writer goroutine, so a slow reader never blocks the hub.
+type Client struct {
+	conn      *websocket.Conn
+	config    ClientConfig
+	queue     []outbound
+	notify    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	dropped   atomic.Uint64
+	mu        sync.Mutex
+}
+
+func NewClient(conn *websocket.Conn, config ClientConfig) *Client {
+	defaults := DefaultClientConfig()
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.WriteWait <= 0 {
+		config.WriteWait = defaults.WriteWait
+	}
+	if config.PingPeriod <= 0 {
+		config.PingPeriod = defaults.PingPeriod
+	}
+	// A ping has to go out before the read deadline passes, or every idle
+	// client times out.
+	if config.PongWait > 0 && config.PingPeriod >= config.PongWait {
+		config.PingPeriod = config.PongWait * 9 / 10
+	}
+
+	c := &Client{
+		conn:   conn,
+		config: config,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	if config.PongWait > 0 {
+		conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		})
+	}
+
+	return c
+}
+
+func (c *Client) ReadJSON(v any) error {
+	return c.conn.ReadJSON(v)
+}
+
+// Send queues a message for the writer goroutine. key identifies the symbol
+// the message belongs to for CoalesceLatest. It returns false when the client
+// is closed or has to be evicted under the Disconnect policy.
+func (c *Client) Send(key string, data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	msg := outbound{key: key, data: data}
+
+	c.mu.Lock()
+	if len(c.queue) >= c.config.QueueSize {
+		// An evicted client's overflow is counted as the eviction only.
+		if c.config.Policy == Disconnect {
+			c.mu.Unlock()
+			return false
+		}
+		c.dropped.Add(1)
+
+		switch c.config.Policy {
+		case CoalesceLatest:
+			if i := c.indexOf(key); i >= 0 {
+				c.queue[i] = msg
+				c.mu.Unlock()
+				return true
+			}
+		}
+		c.queue = c.queue[1:]
+	}
+	c.queue = append(c.queue, msg)
+	c.mu.Unlock()
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// indexOf must be called with c.mu held.
+func (c *Client) indexOf(key string) int {
+	if key == "" {
+		return -1
+	}
+	for i, msg := range c.queue {
+		if msg.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Client) next() (outbound, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.queue) == 0 {
+		return outbound{}, false
+	}
+
+	msg := c.queue[0]
+	c.queue[0] = outbound{}
+	c.queue = c.queue[1:]
+	return msg, true
+}
+
+// WritePump drains the queue and keeps the connection alive with pings until
+// the client is closed or a write fails.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(c.config.PingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+	}()
+
+	for {
+		select {
+		case <-c.notify:
+			for {
+				msg, ok := c.next()
+				if !ok {
+					break
+				}
+
+				c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, msg.data); err != nil {
+					return
+				}
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+func (c *Client) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
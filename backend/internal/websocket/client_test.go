@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientDropOldest(t *testing.T) {
+	config := DefaultClientConfig()
+	config.QueueSize = 2
+	client, _ := newTestClient(t, config)
+
+	client.Send("AAPL", []byte("1"))
+	client.Send("AAPL", []byte("2"))
+	if !client.Send("AAPL", []byte("3")) {
+		t.Fatal("Expected drop oldest policy to keep the client")
+	}
+
+	if client.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", client.Dropped())
+	}
+
+	first, _ := client.next()
+	if string(first.data) != "2" {
+		t.Errorf("Expected oldest message to be dropped, got %s first", first.data)
+	}
+}
+
+func TestClientCoalesceLatest(t *testing.T) {
+	config := DefaultClientConfig()
+	config.QueueSize = 2
+	config.Policy = CoalesceLatest
+	client, _ := newTestClient(t, config)
+
+	client.Send("AAPL", []byte("aapl-1"))
+	client.Send("MSFT", []byte("msft-1"))
+	client.Send("AAPL", []byte("aapl-2"))
+
+	if client.Pending() != 2 {
+		t.Fatalf("Expected 2 pending messages, got %d", client.Pending())
+	}
+
+	first, _ := client.next()
+	second, _ := client.next()
+	if string(first.data) != "aapl-2" || string(second.data) != "msft-1" {
+		t.Errorf("Expected [aapl-2 msft-1], got [%s %s]", first.data, second.data)
+	}
+	if client.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", client.Dropped())
+	}
+}
+
+func TestClientDisconnectPolicy(t *testing.T) {
+	config := DefaultClientConfig()
+	config.QueueSize = 1
+	config.Policy = Disconnect
+	client, _ := newTestClient(t, config)
+
+	if !client.Send("AAPL", []byte("1")) {
+		t.Fatal("Expected first message to be queued")
+	}
+	if client.Send("AAPL", []byte("2")) {
+		t.Error("Expected disconnect policy to reject the client when its queue is full")
+	}
+	if client.Dropped() != 0 {
+		t.Errorf("Expected the eviction not to count as a dropped message, got %d", client.Dropped())
+	}
+}
+
+func TestClientPingsBeforePongWait(t *testing.T) {
+	config := DefaultClientConfig()
+	config.PongWait = time.Second
+	config.PingPeriod = 2 * time.Second
+	client, _ := newTestClient(t, config)
+
+	if client.config.PingPeriod != 900*time.Millisecond {
+		t.Errorf("Expected the ping period clamped to 900ms, got %v", client.config.PingPeriod)
+	}
+}
+
+func TestClientWritePump(t *testing.T) {
+	client, received := newTestClient(t, DefaultClientConfig())
+	go client.WritePump()
+
+	client.Send("AAPL", []byte("hello"))
+
+	select {
+	case msg := <-received:
+		if msg != "hello" {
+			t.Errorf("Expected hello, got %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive message in time")
+	}
+}
+
+func TestHubRemovesFailedClient(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client, _ := newTestClient(t, hub.ClientConfig)
+	// Break the connection so the writer goroutine fails and closes the client.
+	client.conn.UnderlyingConn().Close()
+
+	hub.Register <- client
+	hub.Subscribe <- Subscription{Client: client, Symbol: "AAPL"}
+	hub.Broadcast <- []byte(`{"type":"trade","data":[{"p":1,"s":"AAPL"}]}`)
+
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the writer to close the client after a failed write")
+	}
+
+	hub.Broadcast <- []byte(`{"type":"trade","data":[{"p":2,"s":"AAPL"}]}`)
+	hub.Unsubscribe <- Subscription{}
+
+	if stats := hub.Stats(); stats.Clients != 0 || stats.Symbols != 0 {
+		t.Errorf("Expected failed client and its symbols to be removed, got %+v", stats)
+	}
+}
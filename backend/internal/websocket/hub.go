@@ -1,27 +1,83 @@
 package websocket
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/websocket"
 )
 
+// Upstream is the market data feed the hub subscribes symbols on. A symbol is
+// subscribed when its first client asks for it and released when its last
+// client unsubscribes or disconnects.
+type Upstream interface {
+	Subscribe(symbol string)
+	Unsubscribe(symbol string)
+}
+
+// Wildcard subscribes a client to every symbol the hub relays, for admin
+// views. It is never forwarded upstream.
+const Wildcard = "*"
+
+// Message is a server-generated frame for the clients subscribed to Symbol,
+// or for every client when Symbol is empty. Key groups messages a lagging
+// client may coalesce.
+type Message struct {
+	Symbol string
+	Key    string
+	Data   []byte
+}
+
+type Subscription struct {
+	Client *Client
+	Symbol string
+}
+
 type Hub struct {
-	clients    map[*websocket.Conn]bool
-	Broadcast  chan []byte
-	Register   chan *websocket.Conn
-	Unregister chan *websocket.Conn
-	mu         sync.Mutex
+	clients       map[*Client]map[string]bool
+	refCounts     map[string]int
+	Upstream      Upstream
+	AllowWildcard bool
+	ClientConfig  ClientConfig
+	Broadcast     chan []byte
+	Publish       chan Message
+	Register      chan *Client
+	Unregister    chan *Client
+	Subscribe     chan Subscription
+	Unsubscribe   chan Subscription
+	feedStatus    []byte
+	dropped       uint64
+	evicted       uint64
+	mu            sync.Mutex
+}
+
+type HubStats struct {
+	Clients int    `json:"clients"`
+	Symbols int    `json:"symbols"`
+	Dropped uint64 `json:"dropped"`
+	Evicted uint64 `json:"evicted"`
+}
+
+type StatusMessage struct {
+	Type  string `json:"type"`
+	State string `json:"state"`
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		clients:    make(map[*websocket.Conn]bool),
-		Broadcast:  make(chan []byte),
-		Register:   make(chan *websocket.Conn),
-		Unregister: make(chan *websocket.Conn),
+		clients:      make(map[*Client]map[string]bool),
+		refCounts:    make(map[string]int),
+		ClientConfig: DefaultClientConfig(),
+		Broadcast:    make(chan []byte),
+		Publish:      make(chan Message),
+		Register:     make(chan *Client),
+		Unregister:   make(chan *Client),
+		Subscribe:    make(chan Subscription),
+		Unsubscribe:  make(chan Subscription),
 	}
 }
 
@@ -30,29 +86,241 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.Register:
 			h.mu.Lock()
-			h.clients[client] = true
+			h.clients[client] = make(map[string]bool)
+			go client.WritePump()
+			if h.feedStatus != nil {
+				h.send(client, "", h.feedStatus)
+			}
 			h.mu.Unlock()
 		case client := <-h.Unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close()
-			}
+			h.removeClient(client)
+			h.mu.Unlock()
+		case sub := <-h.Subscribe:
+			h.mu.Lock()
+			h.addSymbol(sub.Client, sub.Symbol)
+			h.mu.Unlock()
+		case sub := <-h.Unsubscribe:
+			h.mu.Lock()
+			h.removeSymbol(sub.Client, sub.Symbol)
 			h.mu.Unlock()
 		case message := <-h.Broadcast:
 			h.mu.Lock()
-			for client := range h.clients {
-				err := client.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					client.Close()
-					delete(h.clients, client)
-				}
-			}
+			h.route(message)
+			h.mu.Unlock()
+		case message := <-h.Publish:
+			h.mu.Lock()
+			h.publish(message)
 			h.mu.Unlock()
 		}
 	}
 }
 
+// SubscriberCount reports how many connected clients are subscribed to symbol.
+func (h *Hub) SubscriberCount(symbol string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.refCounts[symbol]
+}
+
+// Symbols returns the symbols at least one connected client is subscribed to,
+// sorted.
+func (h *Hub) Symbols() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	symbols := make([]string, 0, len(h.refCounts))
+	for symbol := range h.refCounts {
+		symbols = append(symbols, symbol)
+	}
+	slices.Sort(symbols)
+	return symbols
+}
+
+func (h *Hub) Stats() HubStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := HubStats{
+		Clients: len(h.clients),
+		Symbols: len(h.refCounts),
+		Dropped: h.dropped,
+		Evicted: h.evicted,
+	}
+	for client := range h.clients {
+		stats.Dropped += client.Dropped()
+	}
+	return stats
+}
+
+// SetFeedStatus records the state of the upstream feed, sends it to every
+// connected client and replays it to clients that register later.
+func (h *Hub) SetFeedStatus(state string) {
+	message, err := json.Marshal(StatusMessage{Type: "status", State: state})
+	if err != nil {
+		log.Println("Status encode error:", err)
+		return
+	}
+
+	h.mu.Lock()
+	h.feedStatus = message
+	h.mu.Unlock()
+
+	h.Broadcast <- message
+}
+
+type tradeFrame struct {
+	Type string            `json:"type"`
+	Data []json.RawMessage `json:"data"`
+}
+
+// The helpers below must be called with h.mu held.
+
+// route delivers trade frames only to clients subscribed to the traded
+// symbols, splitting multi-symbol frames per client. Any other frame goes to
+// every client.
+func (h *Hub) route(message []byte) {
+	var frame tradeFrame
+	if err := json.Unmarshal(message, &frame); err != nil || frame.Type != "trade" {
+		for client := range h.clients {
+			h.send(client, "", message)
+		}
+		return
+	}
+
+	symbols := make([]string, len(frame.Data))
+	for i, raw := range frame.Data {
+		var trade struct {
+			Symbol string `json:"s"`
+		}
+		if err := json.Unmarshal(raw, &trade); err == nil {
+			symbols[i] = trade.Symbol
+		}
+	}
+
+	for client, subscribed := range h.clients {
+		if subscribed[Wildcard] {
+			h.send(client, coalesceKey(symbols), message)
+			continue
+		}
+
+		var data []json.RawMessage
+		var matched []string
+		for i, raw := range frame.Data {
+			if subscribed[symbols[i]] {
+				data = append(data, raw)
+				matched = append(matched, symbols[i])
+			}
+		}
+
+		switch len(data) {
+		case 0:
+			continue
+		case len(frame.Data):
+			h.send(client, coalesceKey(matched), message)
+		default:
+			filtered, err := json.Marshal(tradeFrame{Type: frame.Type, Data: data})
+			if err != nil {
+				log.Println("Trade encode error:", err)
+				continue
+			}
+			h.send(client, coalesceKey(matched), filtered)
+		}
+	}
+}
+
+func (h *Hub) publish(message Message) {
+	for client, subscribed := range h.clients {
+		if message.Symbol == "" || subscribed[message.Symbol] || subscribed[Wildcard] {
+			h.send(client, message.Key, message.Data)
+		}
+	}
+}
+
+// coalesceKey identifies frames that a lagging client may collapse into the
+// latest one: frames carrying trades for exactly the same symbols.
+func coalesceKey(symbols []string) string {
+	distinct := slices.Clone(symbols)
+	slices.Sort(distinct)
+	return strings.Join(slices.Compact(distinct), ",")
+}
+
+func (h *Hub) send(client *Client, key string, message []byte) {
+	if client.Send(key, message) {
+		return
+	}
+
+	select {
+	case <-client.Done():
+	default:
+		log.Printf("Evicting websocket client: send queue full (policy %s)", h.ClientConfig.Policy)
+		h.evicted++
+	}
+	h.removeClient(client)
+}
+
+func (h *Hub) addSymbol(client *Client, symbol string) {
+	symbols, ok := h.clients[client]
+	if !ok || symbols[symbol] {
+		return
+	}
+
+	if symbol == Wildcard {
+		if !h.AllowWildcard {
+			log.Println("Rejected wildcard subscription: wildcard subscriptions are disabled")
+			return
+		}
+		symbols[symbol] = true
+		return
+	}
+	symbols[symbol] = true
+
+	h.refCounts[symbol]++
+	if h.refCounts[symbol] == 1 && h.Upstream != nil {
+		h.Upstream.Subscribe(symbol)
+	}
+}
+
+func (h *Hub) removeSymbol(client *Client, symbol string) {
+	symbols, ok := h.clients[client]
+	if !ok || !symbols[symbol] {
+		return
+	}
+	delete(symbols, symbol)
+	h.release(symbol)
+}
+
+func (h *Hub) release(symbol string) {
+	if symbol == Wildcard {
+		return
+	}
+
+	h.refCounts[symbol]--
+	if h.refCounts[symbol] > 0 {
+		return
+	}
+
+	delete(h.refCounts, symbol)
+	if h.Upstream != nil {
+		h.Upstream.Unsubscribe(symbol)
+	}
+}
+
+func (h *Hub) removeClient(client *Client) {
+	symbols, ok := h.clients[client]
+	if !ok {
+		return
+	}
+
+	delete(h.clients, client)
+	h.dropped += client.Dropped()
+	client.Close()
+
+	for symbol := range symbols {
+		h.release(symbol)
+	}
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
@@ -63,5 +331,5 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		log.Println("Upgrade error:", err)
 		return
 	}
-	h.Register <- conn
+	h.Register <- NewClient(conn, h.ClientConfig)
 }
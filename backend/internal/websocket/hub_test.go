@@ -1,7 +1,15 @@
 package websocket
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 type MockClient struct {
@@ -18,3 +26,179 @@ func TestHubBroadcast(t *testing.T) {
 		hub.Broadcast <- testMessage
 	}()
 }
+
+type mockUpstream struct {
+	mu           sync.Mutex
+	subscribed   []string
+	unsubscribed []string
+}
+
+func (m *mockUpstream) Subscribe(symbol string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribed = append(m.subscribed, symbol)
+}
+
+func (m *mockUpstream) Unsubscribe(symbol string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unsubscribed = append(m.unsubscribed, symbol)
+}
+
+// newTestClient returns the hub side of a websocket connection and a channel
+// carrying every message the browser side receives.
+func newTestClient(t *testing.T, config ClientConfig) (*Client, <-chan string) {
+	t.Helper()
+
+	received := make(chan string, 16)
+	upgrader := websocket.Upgrader{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		for {
+			_, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}))
+	t.Cleanup(mockServer.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(mockServer.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	client := NewClient(conn, config)
+	t.Cleanup(client.Close)
+
+	return client, received
+}
+
+func TestHubReferenceCounting(t *testing.T) {
+	upstream := &mockUpstream{}
+	hub := NewHub()
+	hub.Upstream = upstream
+	go hub.Run()
+
+	// Run handles one message at a time, so once a no-op is accepted the
+	// previous message has been fully processed.
+	flush := func() { hub.Unsubscribe <- Subscription{} }
+
+	first, _ := newTestClient(t, hub.ClientConfig)
+	second, _ := newTestClient(t, hub.ClientConfig)
+
+	hub.Register <- first
+	hub.Register <- second
+	hub.Subscribe <- Subscription{Client: first, Symbol: "AAPL"}
+	hub.Subscribe <- Subscription{Client: first, Symbol: "AAPL"}
+	hub.Subscribe <- Subscription{Client: second, Symbol: "AAPL"}
+	hub.Subscribe <- Subscription{Client: second, Symbol: "MSFT"}
+	flush()
+
+	if count := hub.SubscriberCount("AAPL"); count != 2 {
+		t.Errorf("Expected 2 AAPL subscribers, got %d", count)
+	}
+	if symbols := hub.Symbols(); !slices.Equal(symbols, []string{"AAPL", "MSFT"}) {
+		t.Errorf("Expected subscribed symbols [AAPL MSFT], got %v", symbols)
+	}
+
+	hub.Unregister <- first
+	hub.Unsubscribe <- Subscription{Client: second, Symbol: "MSFT"}
+	flush()
+
+	if count := hub.SubscriberCount("AAPL"); count != 1 {
+		t.Errorf("Expected 1 AAPL subscriber after unregister, got %d", count)
+	}
+
+	hub.Unregister <- second
+	flush()
+
+	if count := hub.SubscriberCount("AAPL"); count != 0 {
+		t.Errorf("Expected no AAPL subscribers, got %d", count)
+	}
+
+	upstream.mu.Lock()
+	defer upstream.mu.Unlock()
+
+	if !slices.Equal(upstream.subscribed, []string{"AAPL", "MSFT"}) {
+		t.Errorf("Expected upstream subscriptions [AAPL MSFT], got %v", upstream.subscribed)
+	}
+	if !slices.Equal(upstream.unsubscribed, []string{"MSFT", "AAPL"}) {
+		t.Errorf("Expected upstream unsubscriptions [MSFT AAPL], got %v", upstream.unsubscribed)
+	}
+}
+
+func TestHubRoutesTradesBySymbol(t *testing.T) {
+	hub := NewHub()
+	hub.AllowWildcard = true
+	go hub.Run()
+
+	apple, appleMessages := newTestClient(t, hub.ClientConfig)
+	microsoft, microsoftMessages := newTestClient(t, hub.ClientConfig)
+	admin, adminMessages := newTestClient(t, hub.ClientConfig)
+	idle, idleMessages := newTestClient(t, hub.ClientConfig)
+
+	hub.Register <- apple
+	hub.Register <- microsoft
+	hub.Register <- admin
+	hub.Register <- idle
+	hub.Subscribe <- Subscription{Client: apple, Symbol: "AAPL"}
+	hub.Subscribe <- Subscription{Client: microsoft, Symbol: "MSFT"}
+	hub.Subscribe <- Subscription{Client: admin, Symbol: Wildcard}
+
+	frame := `{"type":"trade","data":[{"p":1,"s":"AAPL"},{"p":2,"s":"MSFT"},{"p":3,"s":"AAPL"}]}`
+	hub.Broadcast <- []byte(frame)
+	hub.Broadcast <- []byte(`{"type":"ping"}`)
+
+	expect := func(name string, messages <-chan string, want string) {
+		t.Helper()
+		select {
+		case msg := <-messages:
+			if msg != want {
+				t.Errorf("%s: expected %s, got %s", name, want, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: did not receive %s in time", name, want)
+		}
+	}
+
+	expect("apple", appleMessages, `{"type":"trade","data":[{"p":1,"s":"AAPL"},{"p":3,"s":"AAPL"}]}`)
+	expect("apple", appleMessages, `{"type":"ping"}`)
+	expect("microsoft", microsoftMessages, `{"type":"trade","data":[{"p":2,"s":"MSFT"}]}`)
+	expect("microsoft", microsoftMessages, `{"type":"ping"}`)
+	expect("admin", adminMessages, frame)
+	expect("admin", adminMessages, `{"type":"ping"}`)
+	expect("idle", idleMessages, `{"type":"ping"}`)
+}
+
+func TestHubRejectsWildcardByDefault(t *testing.T) {
+	upstream := &mockUpstream{}
+	hub := NewHub()
+	hub.Upstream = upstream
+	go hub.Run()
+
+	client, messages := newTestClient(t, hub.ClientConfig)
+	hub.Register <- client
+	hub.Subscribe <- Subscription{Client: client, Symbol: Wildcard}
+	hub.Broadcast <- []byte(`{"type":"trade","data":[{"p":1,"s":"AAPL"}]}`)
+	hub.Broadcast <- []byte(`{"type":"ping"}`)
+
+	select {
+	case msg := <-messages:
+		if msg != `{"type":"ping"}` {
+			t.Errorf("Expected only the ping frame, got %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive ping in time")
+	}
+
+	upstream.mu.Lock()
+	defer upstream.mu.Unlock()
+	if len(upstream.subscribed) != 0 {
+		t.Errorf("Expected wildcard not to be forwarded upstream, got %v", upstream.subscribed)
+	}
+}
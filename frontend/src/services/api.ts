@@ -1,5 +1,5 @@
 import axios from 'axios';
-import type { StockQuote } from '../types/types';
+import type { StockQuote, SymbolSearchResponse } from '../types/types';
 
 const API_BASE_URL = import.meta.env.VITE_BACKEND_URL || '';
 
@@ -20,12 +20,22 @@ export default {
     return apiClient.get('/dashboard', { params: { symbol } });
   },
 
+  searchSymbols(q: string, limit = 8) {
+    return apiClient.get<SymbolSearchResponse>('/search', { params: { q, limit } });
+  },
+
   getCompanyNews(symbol: string, from: string, to: string) {
     return apiClient.get('/company-news', {
       params: { symbol, from, to }
     });
   },
 
+  getMarketNews(category = 'general', minId = 0) {
+    return apiClient.get('/news', {
+      params: { category, minId }
+    });
+  },
+
   getMarketStatus(exchange: string) {
     return apiClient.get('market-status', {
       params: { exchange }
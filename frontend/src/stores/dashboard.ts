@@ -2,7 +2,7 @@ import { defineStore } from 'pinia';
 import { computed, ref, watch } from 'vue';
 import api from '../services/api';
 import socket from '../services/socket';
-import type { DashboardData, CompanyNews, MarketStatus } from '../types/types';
+import type { DashboardData, CompanyNews, MarketStatus, NewsMessage } from '../types/types';
 
 export const useDashboardStore = defineStore('dashboard', () => {
   const symbol = ref(localStorage.getItem('stock_symbol') || 'AAPL');
@@ -97,9 +97,27 @@ export const useDashboardStore = defineStore('dashboard', () => {
           dashboardData.value.quote.c = data.p;
         }
       }
+
+      if (data.type === 'news' && data.symbol === symbol.value) {
+        addLiveNews((data as NewsMessage).items);
+      }
     });
   }
 
+  // Live stories are only shown while the selected range runs up to today.
+  function addLiveNews(items: CompanyNews[]) {
+    const to = newsRange.value?.[1];
+    if (!to || to.toDateString() !== new Date().toDateString()) {
+      return;
+    }
+
+    const known = new Set(companyNews.value.map((item) => item.id));
+    const fresh = items.filter((item) => !known.has(item.id));
+    if (fresh.length > 0) {
+      companyNews.value = [...fresh, ...companyNews.value];
+    }
+  }
+
   initSocketListeners();
 
   async function loadDashboard() {
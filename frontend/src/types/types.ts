@@ -9,6 +9,11 @@ export interface StockQuote {
   t: number;
 }
 
+export interface SeriesPoint {
+  period: string;
+  v: number;
+}
+
 export interface BasicFinancials {
   metric: {
     peBasicExclExtraTTM: number;
@@ -17,13 +22,16 @@ export interface BasicFinancials {
     "52WeekLow": number;
     dividendYieldIndicatedAnnual: number;
     beta: number;
+    [name: string]: number | string | null;
   };
   Series?: {
     Annual: {
-      currentRatio?: Array<{ period: string; v: number }>;
-      salesPerShare?: Array<{ period: string; v: number }>;
-      netMargin?: Array<{ period: string; v: number }>;
+      currentRatio?: SeriesPoint[];
+      salesPerShare?: SeriesPoint[];
+      netMargin?: SeriesPoint[];
+      [name: string]: SeriesPoint[] | undefined;
     };
+    Quarterly?: Record<string, SeriesPoint[]>;
   };
   metricType: string;
   symbol: string;
@@ -60,12 +68,43 @@ export interface InsiderTransaction {
   symbol: string;
 }
 
+export interface CompanyProfile {
+  country: string;
+  currency: string;
+  exchange: string;
+  finnhubIndustry: string;
+  ipo: string;
+  logo: string;
+  marketCapitalization: number;
+  name: string;
+  phone: string;
+  shareOutstanding: number;
+  ticker: string;
+  weburl: string;
+}
+
+export interface SymbolMatch {
+  symbol: string;
+  displaySymbol: string;
+  description: string;
+  type: string;
+  mic?: string;
+  exchange?: string;
+  score: number;
+}
+
+export interface SymbolSearchResponse {
+  query: string;
+  results: SymbolMatch[];
+}
+
 export interface DashboardData {
   quote: StockQuote;
   financials: BasicFinancials;
   earnings: EarningsSurprise[];
   recommendations: RecommendationTrend[];
   insiders: InsiderTransaction[];
+  profile?: CompanyProfile;
 }
 
 export interface CompanyNews {
@@ -80,6 +119,12 @@ export interface CompanyNews {
   url: string;
 }
 
+export interface NewsMessage {
+  type: 'news';
+  symbol?: string;
+  items: CompanyNews[];
+}
+
 export interface MarketStatus {
   exchange: string
   holiday: string | null